@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"math/big"
 
 	sdk "github.com/cosmos/cosmos-sdk/types"
@@ -37,13 +38,13 @@ type Info struct {
 	CommunityTax string `json:"communityTax"`
 }
 
-func (h *Handler) GetInfo() (api.Info, error) {
-	info, err := h.Handler.GetInfo()
+func (h *Handler) GetInfo(ctx context.Context) (api.Info, error) {
+	info, err := h.Handler.GetInfo(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	aprData, err := h.getAPRData()
+	aprData, err := h.getAPRData(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -69,18 +70,18 @@ type Account struct {
 	*cosmossdk.Staking
 }
 
-func (h *Handler) GetAccount(pubkey string) (api.Account, error) {
+func (h *Handler) GetAccount(ctx context.Context, pubkey string) (api.Account, error) {
 	a := Account{}
 
-	aprData, err := h.getAPRData()
+	aprData, err := h.getAPRData(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	g := new(errgroup.Group)
+	g, ctx := errgroup.WithContext(ctx)
 
 	g.Go(func() error {
-		account, err := h.Handler.GetAccount(pubkey)
+		account, err := h.Handler.GetAccount(ctx, pubkey)
 		if err != nil {
 			return err
 		}
@@ -91,7 +92,7 @@ func (h *Handler) GetAccount(pubkey string) (api.Account, error) {
 	})
 
 	g.Go(func() error {
-		staking, err := h.GetStaking(pubkey, aprData.bRate)
+		staking, err := h.GetStaking(ctx, pubkey, aprData.bRate)
 		if err != nil {
 			return err
 		}
@@ -108,13 +109,13 @@ func (h *Handler) GetAccount(pubkey string) (api.Account, error) {
 	return a, nil
 }
 
-func (h *Handler) GetValidators(cursor string, pageSize int) (*cosmossdk.Validators, error) {
-	aprData, err := h.getAPRData()
+func (h *Handler) GetValidators(ctx context.Context, cursor string, pageSize int) (*cosmossdk.Validators, error) {
+	aprData, err := h.getAPRData(ctx)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get apr data")
 	}
 
-	res, err := h.HTTPClient.GetValidators(aprData.bRate, cursor, pageSize)
+	res, err := h.HTTPClient.GetValidators(ctx, aprData.bRate, cursor, pageSize)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get validators")
 	}
@@ -129,13 +130,13 @@ func (h *Handler) GetValidators(cursor string, pageSize int) (*cosmossdk.Validat
 	return v, nil
 }
 
-func (h *Handler) GetValidator(address string) (*cosmossdk.Validator, error) {
-	aprData, err := h.getAPRData()
+func (h *Handler) GetValidator(ctx context.Context, address string) (*cosmossdk.Validator, error) {
+	aprData, err := h.getAPRData(ctx)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get apr data")
 	}
 
-	return h.HTTPClient.GetValidator(address, aprData.bRate)
+	return h.HTTPClient.GetValidator(ctx, address, aprData.bRate)
 }
 
 func (h *Handler) ParseMessages(msgs []sdk.Msg, events cosmossdk.EventsByMsgIndex) []cosmossdk.Message {
@@ -159,13 +160,13 @@ type APRData struct {
 	bTotalSupply      *big.Float
 }
 
-func (h *Handler) getAPRData() (*APRData, error) {
+func (h *Handler) getAPRData(ctx context.Context) (*APRData, error) {
 	aprData := &APRData{}
 
-	g := new(errgroup.Group)
+	g, ctx := errgroup.WithContext(ctx)
 
 	g.Go(func() error {
-		totalSupply, err := h.HTTPClient.GetTotalSupply(h.Denom)
+		totalSupply, err := h.HTTPClient.GetTotalSupply(ctx, h.Denom)
 		if err != nil {
 			return err
 		}
@@ -179,7 +180,7 @@ func (h *Handler) getAPRData() (*APRData, error) {
 	})
 
 	g.Go(func() error {
-		annualProvisions, err := h.HTTPClient.GetAnnualProvisions()
+		annualProvisions, err := h.HTTPClient.GetAnnualProvisions(ctx)
 		if err != nil {
 			return err
 		}
@@ -193,7 +194,7 @@ func (h *Handler) getAPRData() (*APRData, error) {
 	})
 
 	g.Go(func() error {
-		communityTax, err := h.HTTPClient.GetCommunityTax()
+		communityTax, err := h.HTTPClient.GetCommunityTax(ctx)
 		if err != nil {
 			return err
 		}
@@ -207,7 +208,7 @@ func (h *Handler) getAPRData() (*APRData, error) {
 	})
 
 	g.Go(func() error {
-		bondedTokens, err := h.HTTPClient.GetBondedTokens()
+		bondedTokens, err := h.HTTPClient.GetBondedTokens(ctx)
 		if err != nil {
 			return err
 		}
@@ -239,13 +240,13 @@ func (h *Handler) getAPRData() (*APRData, error) {
 // swagger:model Fees
 type Fees map[string]string
 
-func (h *Handler) GetFees() (*Fees, error) {
-	globalMinGasPrices, err := h.HTTPClient.GetGlobalMinimumGasPrices()
+func (h *Handler) GetFees(ctx context.Context) (*Fees, error) {
+	globalMinGasPrices, err := h.HTTPClient.GetGlobalMinimumGasPrices(ctx)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get global minimum gas prices")
 	}
 
-	localMinGasPrice, err := h.HTTPClient.GetLocalMinimumGasPrices()
+	localMinGasPrice, err := h.HTTPClient.GetLocalMinimumGasPrices(ctx)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get local minimum gas prices")
 	}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
 	"os/signal"
@@ -13,6 +14,7 @@ import (
 	"github.com/shapeshift/unchained/shared/cosmossdk"
 	"github.com/shapeshift/unchained/shared/log"
 	"github.com/shapeshift/unchained/shared/metrics"
+	"github.com/shapeshift/unchained/shared/tracing"
 )
 
 var (
@@ -70,10 +72,15 @@ func main() {
 
 	prometheus := metrics.NewPrometheus("cosmos")
 
+	shutdownTracing, err := tracing.Start("cosmos")
+	if err != nil {
+		logger.Panicf("failed to start tracing: %+v", err)
+	}
+
 	sdk.GetConfig().SetBech32PrefixForAccount(cfg.Bech32AddrPrefix, cfg.Bech32PkPrefix)
 	sdk.GetConfig().SetBech32PrefixForValidator(cfg.Bech32ValPrefix, cfg.Bech32PkValPrefix)
 
-	httpClient, err := cosmos.NewHTTPClient(cfg)
+	httpClient, err := cosmos.NewHTTPClient(cfg, prometheus)
 	if err != nil {
 		logger.Panicf("failed to create new http client: %+v", err)
 	}
@@ -83,7 +90,7 @@ func main() {
 		logger.Panicf("failed to create new block service: %+v", err)
 	}
 
-	wsClient, err := cosmos.NewWebsocketClient(cfg, blockService, errChan)
+	wsClient, err := cosmos.NewWebsocketClient(cfg, httpClient, blockService, errChan, prometheus)
 	if err != nil {
 		logger.Panicf("failed to create new websocket client: %+v", err)
 	}
@@ -98,6 +105,9 @@ func main() {
 		logger.Panicf("%+v", err)
 	case <-sigChan:
 		api.Shutdown()
+		if err := shutdownTracing(context.Background()); err != nil {
+			logger.Errorf("failed to shutdown tracing: %+v", err)
+		}
 		os.Exit(0)
 	}
 }
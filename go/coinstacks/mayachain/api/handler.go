@@ -1,6 +1,8 @@
 package api
 
 import (
+	"context"
+
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/shapeshift/unchained/pkg/mayachain"
 	"github.com/shapeshift/unchained/shared/api"
@@ -18,8 +20,8 @@ type Info struct {
 	cosmossdk.Info
 }
 
-func (h *Handler) GetInfo() (api.Info, error) {
-	info, err := h.Handler.GetInfo()
+func (h *Handler) GetInfo(ctx context.Context) (api.Info, error) {
+	info, err := h.Handler.GetInfo(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -36,8 +38,8 @@ type Account struct {
 	cosmossdk.Account
 }
 
-func (h *Handler) GetAccount(pubkey string) (api.Account, error) {
-	account, err := h.Handler.GetAccount(pubkey)
+func (h *Handler) GetAccount(ctx context.Context, pubkey string) (api.Account, error) {
+	account, err := h.Handler.GetAccount(ctx, pubkey)
 	if err != nil {
 		return nil, err
 	}
@@ -47,8 +49,8 @@ func (h *Handler) GetAccount(pubkey string) (api.Account, error) {
 	return a, nil
 }
 
-func (h *Handler) GetTxHistory(pubkey string, cursor string, pageSize int) (api.TxHistory, error) {
-	return mayachain.GetTxHistory(h.Handler, pubkey, cursor, pageSize)
+func (h *Handler) GetTxHistory(ctx context.Context, pubkey string, cursor string, pageSize int) (api.TxHistory, error) {
+	return mayachain.GetTxHistory(ctx, h.Handler, pubkey, cursor, pageSize)
 }
 
 func (h *Handler) ParseMessages(msgs []sdk.Msg, events cosmossdk.EventsByMsgIndex) []cosmossdk.Message {
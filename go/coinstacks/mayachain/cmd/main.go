@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
 	"os/signal"
@@ -13,6 +14,7 @@ import (
 	"github.com/shapeshift/unchained/shared/cosmossdk"
 	"github.com/shapeshift/unchained/shared/log"
 	"github.com/shapeshift/unchained/shared/metrics"
+	"github.com/shapeshift/unchained/shared/tracing"
 
 	mayatypes "gitlab.com/mayachain/mayanode/x/mayachain/types"
 )
@@ -76,9 +78,14 @@ func main() {
 
 	prometheus := metrics.NewPrometheus("mayachain")
 
+	shutdownTracing, err := tracing.Start("mayachain")
+	if err != nil {
+		logger.Panicf("failed to start tracing: %+v", err)
+	}
+
 	sdk.GetConfig().SetBech32PrefixForAccount(cfg.Bech32AddrPrefix, cfg.Bech32PkPrefix)
 
-	httpClient, err := mayachain.NewHTTPClient(cfg)
+	httpClient, err := mayachain.NewHTTPClient(cfg, prometheus)
 	if err != nil {
 		logger.Panicf("failed to create new http client: %+v", err)
 	}
@@ -88,7 +95,7 @@ func main() {
 		logger.Panicf("failed to create new block service: %+v", err)
 	}
 
-	wsClient, err := mayachain.NewWebsocketClient(cfg, blockService, errChan)
+	wsClient, err := mayachain.NewWebsocketClient(cfg, httpClient, blockService, errChan, prometheus)
 	if err != nil {
 		logger.Panicf("failed to create new websocket client: %+v", err)
 	}
@@ -103,6 +110,9 @@ func main() {
 		logger.Panicf("%+v", err)
 	case <-sigChan:
 		api.Shutdown()
+		if err := shutdownTracing(context.Background()); err != nil {
+			logger.Errorf("failed to shutdown tracing: %+v", err)
+		}
 		os.Exit(0)
 	}
 }
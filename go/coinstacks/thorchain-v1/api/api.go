@@ -103,7 +103,12 @@ func New(cfg thorchain.Config, httpClient *thorchain.HTTPClient, wsClient *thorc
 		logger.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", PPROF_PORT), http.DefaultServeMux))
 	}()
 
-	r.Use(api.Scheme, api.Logger(prometheus))
+	auth, err := api.NewAuthFromEnv(prometheus)
+	if err != nil {
+		logger.Panicf("failed to create api key auth: %+v", err)
+	}
+
+	r.Use(api.Scheme, api.Tracing, api.Logger(prometheus), auth.Middleware)
 
 	r.HandleFunc("/", api.DocsRedirect).Methods("GET")
 
@@ -111,6 +116,10 @@ func New(cfg thorchain.Config, httpClient *thorchain.HTTPClient, wsClient *thorc
 		api.HandleResponse(w, http.StatusOK, map[string]string{"status": "up", "coinstack": "thorchain-v1", "connections": strconv.Itoa(manager.ConnectionCount())})
 	}).Methods("GET")
 
+	health := api.NewHealth("thorchain-v1", handler.HealthChecks()...)
+	r.HandleFunc("/health/live", health.Live).Methods("GET")
+	r.HandleFunc("/health/ready", health.Ready).Methods("GET")
+
 	r.Handle("/metrics", promhttp.HandlerFor(prometheus.Registry, promhttp.HandlerOpts{}))
 
 	r.HandleFunc("/swagger", func(w http.ResponseWriter, r *http.Request) {
@@ -169,7 +178,10 @@ func (a *API) TxHistory(w http.ResponseWriter, r *http.Request) {
 //
 //	200: Tx
 //	400: BadRequestError
+//	404: ApiError
 //	500: InternalServerError
+//	502: ApiError
+//	504: ApiError
 func (a *API) Tx(w http.ResponseWriter, r *http.Request) {
 	a.API.Tx(w, r)
 }
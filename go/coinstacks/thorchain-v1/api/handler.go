@@ -1,6 +1,8 @@
 package api
 
 import (
+	"context"
+
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/pkg/errors"
 	"github.com/shapeshift/unchained/pkg/thorchain"
@@ -21,6 +23,15 @@ func (h *Handler) StartWebsocket() error {
 	return nil
 }
 
+// HealthChecks excludes the latest block age as the archived v1 chain no longer produces blocks
+func (h *Handler) HealthChecks() []api.HealthCheck {
+	return []api.HealthCheck{
+		{Name: "lcd", Check: h.HTTPClient.CheckLCD},
+		{Name: "rpc", Check: h.HTTPClient.CheckRPC},
+		{Name: "websocket", Check: h.WSClient.CheckSubscriptions},
+	}
+}
+
 // Contains info about the running coinstack
 // swagger:model Info
 type Info struct {
@@ -28,8 +39,8 @@ type Info struct {
 	cosmossdk.Info
 }
 
-func (h *Handler) GetInfo() (api.Info, error) {
-	info, err := h.Handler.GetInfo()
+func (h *Handler) GetInfo(ctx context.Context) (api.Info, error) {
+	info, err := h.Handler.GetInfo(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -39,8 +50,8 @@ func (h *Handler) GetInfo() (api.Info, error) {
 	return i, nil
 }
 
-func (h *Handler) GetTxHistory(pubkey string, cursor string, pageSize int) (api.TxHistory, error) {
-	return thorchain.GetTxHistory(h.Handler, pubkey, cursor, pageSize)
+func (h *Handler) GetTxHistory(ctx context.Context, pubkey string, cursor string, pageSize int) (api.TxHistory, error) {
+	return thorchain.GetTxHistory(ctx, h.Handler, pubkey, cursor, pageSize)
 }
 
 func (h *Handler) ParseMessages(msgs []sdk.Msg, events cosmossdk.EventsByMsgIndex) []cosmossdk.Message {
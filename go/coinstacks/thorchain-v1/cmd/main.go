@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
 	"os/signal"
@@ -13,6 +14,7 @@ import (
 	"github.com/shapeshift/unchained/shared/cosmossdk"
 	"github.com/shapeshift/unchained/shared/log"
 	"github.com/shapeshift/unchained/shared/metrics"
+	"github.com/shapeshift/unchained/shared/tracing"
 
 	thortypes "gitlab.com/thorchain/thornode/v3/x/thorchain/types"
 )
@@ -72,9 +74,14 @@ func main() {
 
 	prometheus := metrics.NewPrometheus("thorchain-v1")
 
+	shutdownTracing, err := tracing.Start("thorchain-v1")
+	if err != nil {
+		logger.Panicf("failed to start tracing: %+v", err)
+	}
+
 	sdk.GetConfig().SetBech32PrefixForAccount(cfg.Bech32AddrPrefix, cfg.Bech32PkPrefix)
 
-	httpClient, err := thorchain.NewHTTPClient(cfg)
+	httpClient, err := thorchain.NewHTTPClient(cfg, prometheus)
 	if err != nil {
 		logger.Panicf("failed to create new http client: %+v", err)
 	}
@@ -84,7 +91,7 @@ func main() {
 		logger.Panicf("failed to create new block service: %+v", err)
 	}
 
-	wsClient, err := thorchain.NewWebsocketClient(cfg, blockService, errChan)
+	wsClient, err := thorchain.NewWebsocketClient(cfg, httpClient, blockService, errChan, prometheus)
 	if err != nil {
 		logger.Panicf("failed to create new websocket client: %+v", err)
 	}
@@ -99,6 +106,9 @@ func main() {
 		logger.Panicf("%+v", err)
 	case <-sigChan:
 		api.Shutdown()
+		if err := shutdownTracing(context.Background()); err != nil {
+			logger.Errorf("failed to shutdown tracing: %+v", err)
+		}
 		os.Exit(0)
 	}
 }
@@ -1,6 +1,7 @@
 package thorchainV1
 
 import (
+	"context"
 	"strconv"
 
 	"github.com/pkg/errors"
@@ -43,10 +44,10 @@ func (r *ResultBlockResults) GetBlockEvents() []cosmossdk.ABCIEvent {
 	return blockEvents
 }
 
-func (c *HTTPClient) BlockResults(height int) (cosmossdk.BlockResults, error) {
+func (c *HTTPClient) BlockResults(ctx context.Context, height int) (cosmossdk.BlockResults, error) {
 	res := &rpctypes.RPCResponse{}
 
-	_, err := c.RPC.R().SetResult(res).SetError(res).SetQueryParam("height", strconv.Itoa(height)).Get("/block_results")
+	_, err := c.RPC.R().SetContext(ctx).SetResult(res).SetError(res).SetQueryParam("height", strconv.Itoa(height)).Get("/block_results")
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to get block results for block: %v", height)
 	}
@@ -34,6 +34,7 @@ import (
 	"github.com/shapeshift/unchained/shared/cosmossdk"
 	"github.com/shapeshift/unchained/shared/log"
 	"github.com/shapeshift/unchained/shared/metrics"
+	"github.com/shapeshift/unchained/shared/webhook"
 	"github.com/shapeshift/unchained/shared/websocket"
 )
 
@@ -103,12 +104,24 @@ func New(cfg thorchain.Config, httpClient *thorchain.HTTPClient, wsClient *thorc
 		logger.Panicf("%+v", err)
 	}
 
+	webhooks, err := webhook.NewService(wsClient, thorchain.IsValidAddress, prometheus, webhook.ConfigFromEnv())
+	if err != nil {
+		logger.Panicf("failed to create webhook service: %+v", err)
+	}
+
+	a.API.WebhookService(webhooks)
+
 	// pprof server
 	go func() {
 		logger.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", PPROF_PORT), http.DefaultServeMux))
 	}()
 
-	r.Use(api.Scheme, api.Logger(prometheus))
+	auth, err := api.NewAuthFromEnv(prometheus)
+	if err != nil {
+		logger.Panicf("failed to create api key auth: %+v", err)
+	}
+
+	r.Use(api.Scheme, api.Tracing, api.Logger(prometheus), auth.Middleware)
 
 	r.HandleFunc("/", a.Root).Methods("GET")
 
@@ -116,6 +129,10 @@ func New(cfg thorchain.Config, httpClient *thorchain.HTTPClient, wsClient *thorc
 		api.HandleResponse(w, http.StatusOK, map[string]string{"status": "up", "coinstack": "thorchain", "connections": strconv.Itoa(manager.ConnectionCount())})
 	}).Methods("GET")
 
+	health := api.NewHealth("thorchain", handler.HealthChecks()...)
+	r.HandleFunc("/health/live", health.Live).Methods("GET")
+	r.HandleFunc("/health/ready", health.Ready).Methods("GET")
+
 	r.Handle("/metrics", promhttp.HandlerFor(prometheus.Registry, promhttp.HandlerOpts{}))
 
 	r.HandleFunc("/swagger", func(w http.ResponseWriter, r *http.Request) {
@@ -132,6 +149,16 @@ func New(cfg thorchain.Config, httpClient *thorchain.HTTPClient, wsClient *thorc
 	v1Account.Use(cosmossdk.ValidatePubkeyMiddleware(thorchain.IsValidAddress))
 	v1Account.HandleFunc("/{pubkey}", a.Account).Methods("GET")
 	v1Account.HandleFunc("/{pubkey}/txs", a.TxHistory).Methods("GET")
+	v1Account.HandleFunc("/{pubkey}/stream", a.Stream).Methods("GET")
+
+	v1Webhooks := v1.PathPrefix("/webhooks").Subrouter()
+	// webhooks post to caller supplied urls, so they always require an api key
+	v1Webhooks.Use(auth.RequireAPIKey)
+	v1Webhooks.HandleFunc("", a.RegisterWebhook).Methods("POST")
+	v1Webhooks.HandleFunc("/{id}", a.GetWebhook).Methods("GET")
+	v1Webhooks.HandleFunc("/{id}", a.DeleteWebhook).Methods("DELETE")
+	v1Webhooks.HandleFunc("/{id}/deadletters", a.WebhookDeadLetters).Methods("GET")
+	v1Webhooks.HandleFunc("/{id}/deadletters/{deliveryId}/redeliver", a.RedeliverWebhook).Methods("POST")
 
 	v1Transaction := v1.PathPrefix("/tx").Subrouter()
 	v1Transaction.HandleFunc("/{txid}", a.Tx).Methods("GET")
@@ -200,6 +227,93 @@ func (a *API) TxHistory(w http.ResponseWriter, r *http.Request) {
 	a.API.TxHistory(w, r)
 }
 
+// swagger:route GET /api/v1/account/{pubkey}/stream v1 Stream
+//
+// Stream confirmed transactions as server-sent events.
+//
+// produces:
+// - text/event-stream
+//
+// responses:
+//
+//	200:
+//	400: BadRequestError
+func (a *API) Stream(w http.ResponseWriter, r *http.Request) {
+	a.API.Stream(w, r)
+}
+
+// swagger:route POST /api/v1/webhooks v1 RegisterWebhook
+//
+// Register a url to receive a signed delivery for every transaction involving any of the addresses.
+//
+// responses:
+//
+//	201: Webhook
+//	400: BadRequestError
+//	401: ApiError
+//	500: InternalServerError
+//	503: ApiError
+func (a *API) RegisterWebhook(w http.ResponseWriter, r *http.Request) {
+	a.API.RegisterWebhook(w, r)
+}
+
+// swagger:route GET /api/v1/webhooks/{id} v1 GetWebhook
+//
+// Get a registered webhook.
+//
+// responses:
+//
+//	200: Webhook
+//	401: ApiError
+//	404: ApiError
+//	503: ApiError
+func (a *API) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	a.API.GetWebhook(w, r)
+}
+
+// swagger:route DELETE /api/v1/webhooks/{id} v1 DeleteWebhook
+//
+// Delete a registered webhook.
+//
+// responses:
+//
+//	204:
+//	401: ApiError
+//	404: ApiError
+//	503: ApiError
+func (a *API) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	a.API.DeleteWebhook(w, r)
+}
+
+// swagger:route GET /api/v1/webhooks/{id}/deadletters v1 WebhookDeadLetters
+//
+// Get the deliveries for a webhook that failed all attempts.
+//
+// responses:
+//
+//	200: WebhookDeliveries
+//	401: ApiError
+//	404: ApiError
+//	503: ApiError
+func (a *API) WebhookDeadLetters(w http.ResponseWriter, r *http.Request) {
+	a.API.WebhookDeadLetters(w, r)
+}
+
+// swagger:route POST /api/v1/webhooks/{id}/deadletters/{deliveryId}/redeliver v1 RedeliverWebhook
+//
+// Retry a dead lettered delivery.
+//
+// responses:
+//
+//	202:
+//	401: ApiError
+//	404: ApiError
+//	500: InternalServerError
+//	503: ApiError
+func (a *API) RedeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	a.API.RedeliverWebhook(w, r)
+}
+
 // swagger:route GET /api/v1/tx/{txid} v1 GetTx
 //
 // # Get transaction details
@@ -208,7 +322,10 @@ func (a *API) TxHistory(w http.ResponseWriter, r *http.Request) {
 //
 //	200: Tx
 //	400: BadRequestError
+//	404: ApiError
 //	500: InternalServerError
+//	502: ApiError
+//	504: ApiError
 func (a *API) Tx(w http.ResponseWriter, r *http.Request) {
 	a.API.Tx(w, r)
 }
@@ -221,7 +338,10 @@ func (a *API) Tx(w http.ResponseWriter, r *http.Request) {
 //
 //	200: TransactionHash
 //	400: BadRequestError
+//	422: ApiError
 //	500: InternalServerError
+//	502: ApiError
+//	504: ApiError
 func (a *API) SendTx(w http.ResponseWriter, r *http.Request) {
 	a.API.SendTx(w, r)
 }
@@ -252,12 +372,12 @@ func (a *API) LCD(w http.ResponseWriter, r *http.Request) {
 		path = "/"
 	}
 
-	req := a.httpClient.LCD.R()
+	req := a.httpClient.LCD.R().SetContext(r.Context())
 	req.QueryParam = r.URL.Query()
 
 	res, err := req.Get(path)
 	if err != nil {
-		api.HandleError(w, http.StatusInternalServerError, err.Error())
+		api.HandleAPIError(w, err)
 		return
 	}
 
@@ -285,7 +405,7 @@ func (a *API) RPC(w http.ResponseWriter, r *http.Request) {
 		path = "/"
 	}
 
-	req := a.httpClient.RPC.R()
+	req := a.httpClient.RPC.R().SetContext(r.Context())
 	req.QueryParam = r.URL.Query()
 
 	var res *resty.Response
@@ -295,7 +415,7 @@ func (a *API) RPC(w http.ResponseWriter, r *http.Request) {
 	case http.MethodGet:
 		res, err = req.Get(path)
 		if err != nil {
-			api.HandleError(w, http.StatusInternalServerError, err.Error())
+			api.HandleAPIError(w, err)
 			return
 		}
 	case http.MethodPost:
@@ -311,7 +431,7 @@ func (a *API) RPC(w http.ResponseWriter, r *http.Request) {
 
 		res, err = req.Post(path)
 		if err != nil {
-			api.HandleError(w, http.StatusInternalServerError, err.Error())
+			api.HandleAPIError(w, err)
 			return
 		}
 	default:
@@ -336,12 +456,12 @@ func (a *API) Midgard(w http.ResponseWriter, r *http.Request) {
 		path = "/"
 	}
 
-	req := a.httpClient.Indexer.R()
+	req := a.httpClient.Indexer.R().SetContext(r.Context())
 	req.QueryParam = r.URL.Query()
 
 	res, err := req.Get(path)
 	if err != nil {
-		api.HandleError(w, http.StatusInternalServerError, err.Error())
+		api.HandleAPIError(w, err)
 		return
 	}
 
@@ -356,7 +476,7 @@ func (a *API) handleProxyResponse(w http.ResponseWriter, res *resty.Response) {
 
 	var result any
 	if err := json.Unmarshal(res.Body(), &result); err != nil {
-		api.HandleError(w, http.StatusInternalServerError, err.Error())
+		api.HandleAPIError(w, err)
 		return
 	}
 
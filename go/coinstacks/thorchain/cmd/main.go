@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
 	"os/signal"
@@ -14,6 +15,7 @@ import (
 	"github.com/shapeshift/unchained/shared/cosmossdk"
 	"github.com/shapeshift/unchained/shared/log"
 	"github.com/shapeshift/unchained/shared/metrics"
+	"github.com/shapeshift/unchained/shared/tracing"
 
 	"gitlab.com/thorchain/thornode/v3/x/thorchain/ebifrost"
 	thortypes "gitlab.com/thorchain/thornode/v3/x/thorchain/types"
@@ -92,9 +94,14 @@ func main() {
 
 	prometheus := metrics.NewPrometheus("thorchain")
 
+	shutdownTracing, err := tracing.Start("thorchain")
+	if err != nil {
+		logger.Panicf("failed to start tracing: %+v", err)
+	}
+
 	sdk.GetConfig().SetBech32PrefixForAccount(cfg.Bech32AddrPrefix, cfg.Bech32PkPrefix)
 
-	httpClient, err := thorchain.NewHTTPClient(cfg)
+	httpClient, err := thorchain.NewHTTPClient(cfg, prometheus)
 	if err != nil {
 		logger.Panicf("failed to create new http client: %+v", err)
 	}
@@ -104,7 +111,7 @@ func main() {
 		logger.Panicf("failed to create new block service: %+v", err)
 	}
 
-	wsClient, err := thorchain.NewWebsocketClient(cfg, blockService, errChan)
+	wsClient, err := thorchain.NewWebsocketClient(cfg, httpClient, blockService, errChan, prometheus)
 	if err != nil {
 		logger.Panicf("failed to create new websocket client: %+v", err)
 	}
@@ -119,6 +126,9 @@ func main() {
 		logger.Panicf("%+v", err)
 	case <-sigChan:
 		api.Shutdown()
+		if err := shutdownTracing(context.Background()); err != nil {
+			logger.Errorf("failed to shutdown tracing: %+v", err)
+		}
 		os.Exit(0)
 	}
 }
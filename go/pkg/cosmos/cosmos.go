@@ -2,6 +2,8 @@
 package cosmos
 
 import (
+	"context"
+
 	sdkmath "cosmossdk.io/math"
 	"cosmossdk.io/simapp/params"
 	wasmtypes "github.com/CosmWasm/wasmd/x/wasm/types"
@@ -26,6 +28,7 @@ import (
 	ibclightclientstendermint "github.com/cosmos/ibc-go/v10/modules/light-clients/07-tendermint"
 	"github.com/shapeshift/unchained/shared/cosmossdk"
 	"github.com/shapeshift/unchained/shared/log"
+	"github.com/shapeshift/unchained/shared/metrics"
 )
 
 var logger = log.WithoutFields()
@@ -38,22 +41,22 @@ type APIClient interface {
 	cosmossdk.APIClient
 
 	// Block
-	BlockSearch(query string, page int, pageSize int) (*coretypes.ResultBlockSearch, error)
+	BlockSearch(ctx context.Context, query string, page int, pageSize int) (*coretypes.ResultBlockSearch, error)
 
 	// Fees/Gas
-	GetGlobalMinimumGasPrices() (map[string]sdkmath.LegacyDec, error)
-	GetLocalMinimumGasPrices() (map[string]sdkmath.LegacyDec, error)
+	GetGlobalMinimumGasPrices(ctx context.Context) (map[string]sdkmath.LegacyDec, error)
+	GetLocalMinimumGasPrices(ctx context.Context) (map[string]sdkmath.LegacyDec, error)
 
 	// Transactions
-	GetTx(txid string) (*coretypes.ResultTx, error)
-	TxSearch(query string, page int, pageSize int) (*coretypes.ResultTxSearch, error)
+	GetTx(ctx context.Context, txid string) (*coretypes.ResultTx, error)
+	TxSearch(ctx context.Context, query string, page int, pageSize int) (*coretypes.ResultTxSearch, error)
 
 	// Utility
 	GetEncoding() *params.EncodingConfig
 }
 
-func NewHTTPClient(conf cosmossdk.Config) (*HTTPClient, error) {
-	httpClient, err := cosmossdk.NewHTTPClient(conf)
+func NewHTTPClient(conf cosmossdk.Config, prometheus *metrics.Prometheus) (*HTTPClient, error) {
+	httpClient, err := cosmossdk.NewHTTPClient(conf, prometheus)
 	if err != nil {
 		logger.Panicf("failed to create new http client: %+v", err)
 	}
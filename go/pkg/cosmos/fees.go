@@ -1,6 +1,7 @@
 package cosmos
 
 import (
+	"context"
 	"fmt"
 
 	sdkmath "cosmossdk.io/math"
@@ -9,7 +10,7 @@ import (
 	"github.com/shapeshift/unchained/shared/cosmossdk"
 )
 
-func (c *HTTPClient) GetGlobalMinimumGasPrices() (map[string]sdkmath.LegacyDec, error) {
+func (c *HTTPClient) GetGlobalMinimumGasPrices(ctx context.Context) (map[string]sdkmath.LegacyDec, error) {
 	gasPrices := make(map[string]sdkmath.LegacyDec)
 
 	var res struct {
@@ -22,7 +23,7 @@ func (c *HTTPClient) GetGlobalMinimumGasPrices() (map[string]sdkmath.LegacyDec,
 	e := &cosmossdk.ErrorResponse{}
 
 	url := fmt.Sprintf("/feemarket/v1/gas_price/%s", c.Denom)
-	r, err := c.LCD.R().SetResult(&res).SetError(e).Get(url)
+	r, err := c.LCD.R().SetContext(ctx).SetResult(&res).SetError(e).Get(url)
 	if err != nil {
 		return gasPrices, errors.Wrap(err, "failed to get globalfee params")
 	}
@@ -41,7 +42,7 @@ func (c *HTTPClient) GetGlobalMinimumGasPrices() (map[string]sdkmath.LegacyDec,
 	return gasPrices, nil
 }
 
-func (c *HTTPClient) GetLocalMinimumGasPrices() (map[string]sdkmath.LegacyDec, error) {
+func (c *HTTPClient) GetLocalMinimumGasPrices(ctx context.Context) (map[string]sdkmath.LegacyDec, error) {
 	gasPrices := make(map[string]sdkmath.LegacyDec)
 
 	var res struct {
@@ -50,7 +51,7 @@ func (c *HTTPClient) GetLocalMinimumGasPrices() (map[string]sdkmath.LegacyDec, e
 
 	e := &cosmossdk.ErrorResponse{}
 
-	r, err := c.LCD.R().SetResult(&res).SetError(e).Get("/cosmos/base/node/v1beta1/config")
+	r, err := c.LCD.R().SetContext(ctx).SetResult(&res).SetError(e).Get("/cosmos/base/node/v1beta1/config")
 	if err != nil {
 		return gasPrices, errors.Wrap(err, "failed to get base node config")
 	}
@@ -1,6 +1,7 @@
 package cosmos
 
 import (
+	"context"
 	"fmt"
 
 	coretypes "github.com/cometbft/cometbft/rpc/core/types"
@@ -8,9 +9,9 @@ import (
 	"github.com/shapeshift/unchained/shared/cosmossdk"
 )
 
-func TxHistorySources(client APIClient, pubkey string, formatTx func(*coretypes.ResultTx) (*cosmossdk.Tx, error)) map[string]*cosmossdk.TxState {
-	request := func(query string, page int, pageSize int) ([]cosmossdk.HistoryTx, error) {
-		result, err := client.TxSearch(query, page, pageSize)
+func TxHistorySources(client APIClient, pubkey string, formatTx func(context.Context, *coretypes.ResultTx) (*cosmossdk.Tx, error)) map[string]*cosmossdk.TxState {
+	request := func(ctx context.Context, query string, page int, pageSize int) ([]cosmossdk.HistoryTx, error) {
+		result, err := client.TxSearch(ctx, query, page, pageSize)
 		if err != nil {
 			return nil, errors.WithStack(err)
 		}
@@ -29,9 +30,9 @@ func TxHistorySources(client APIClient, pubkey string, formatTx func(*coretypes.
 	}
 }
 
-func ValidatorTxHistorySources(client APIClient, pubkey string, formatTx func(*coretypes.ResultTx) (*cosmossdk.Tx, error)) map[string]*cosmossdk.TxState {
-	request := func(query string, page int, pageSize int) ([]cosmossdk.HistoryTx, error) {
-		result, err := client.TxSearch(query, page, pageSize)
+func ValidatorTxHistorySources(client APIClient, pubkey string, formatTx func(context.Context, *coretypes.ResultTx) (*cosmossdk.Tx, error)) map[string]*cosmossdk.TxState {
+	request := func(ctx context.Context, query string, page int, pageSize int) ([]cosmossdk.HistoryTx, error) {
+		result, err := client.TxSearch(ctx, query, page, pageSize)
 		if err != nil {
 			return nil, errors.WithStack(err)
 		}
@@ -1,13 +1,15 @@
 package cosmos
 
 import (
+	"context"
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"strconv"
 	"strings"
 
-	sdkerrors "cosmossdk.io/errors"
 	sdkmath "cosmossdk.io/math"
 	"cosmossdk.io/simapp/params"
 	abcitypes "github.com/cometbft/cometbft/abci/types"
@@ -17,6 +19,7 @@ import (
 	cometbfttypes "github.com/cometbft/cometbft/types"
 	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
 	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
 	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
 	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
 	distributiontypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
@@ -24,23 +27,34 @@ import (
 	ibctransfertypes "github.com/cosmos/ibc-go/v10/modules/apps/transfer/types"
 	ibcchanneltypes "github.com/cosmos/ibc-go/v10/modules/core/04-channel/types"
 	"github.com/pkg/errors"
+	"github.com/shapeshift/unchained/shared/api"
 	"github.com/shapeshift/unchained/shared/cosmossdk"
 )
 
-func (c *HTTPClient) GetTx(txid string) (*coretypes.ResultTx, error) {
+func (c *HTTPClient) GetTx(ctx context.Context, txid string) (*coretypes.ResultTx, error) {
 	res := &rpctypes.RPCResponse{}
 
 	if !strings.HasPrefix(txid, "0x") {
 		txid = "0x" + txid
 	}
 
-	_, err := c.RPC.R().SetResult(res).SetError(res).SetQueryParam("hash", txid).Get("/tx")
+	_, err := c.RPC.R().SetContext(ctx).SetResult(res).SetError(res).SetQueryParam("hash", txid).Get("/tx")
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to get tx: %s", txid)
+		return nil, api.NewUpstreamError(err, fmt.Sprintf("failed to get tx: %s", txid))
 	}
 
 	if res.Error != nil {
-		return nil, errors.Wrapf(errors.New(res.Error.Error()), "failed to get tx: %s", txid)
+		// rpc responds with a generic internal error if the tx does not exist, so confirm with the lcd node instead
+		exists, err := c.TxExists(ctx, txid)
+		if err != nil {
+			return nil, err
+		}
+
+		if !exists {
+			return nil, api.NewTxNotFoundError(strings.TrimPrefix(txid, "0x"))
+		}
+
+		return nil, api.NewUpstreamError(errors.New(res.Error.Error()), fmt.Sprintf("failed to get tx: %s", txid))
 	}
 
 	tx := &coretypes.ResultTx{}
@@ -51,7 +65,7 @@ func (c *HTTPClient) GetTx(txid string) (*coretypes.ResultTx, error) {
 	return tx, nil
 }
 
-func (c *HTTPClient) TxSearch(query string, page int, pageSize int) (*coretypes.ResultTxSearch, error) {
+func (c *HTTPClient) TxSearch(ctx context.Context, query string, page int, pageSize int) (*coretypes.ResultTxSearch, error) {
 	res := &rpctypes.RPCResponse{}
 
 	queryParams := map[string]string{
@@ -61,16 +75,16 @@ func (c *HTTPClient) TxSearch(query string, page int, pageSize int) (*coretypes.
 		"order_by": "\"desc\"",
 	}
 
-	_, err := c.RPC.R().SetResult(res).SetError(res).SetQueryParams(queryParams).Get("/tx_search")
+	_, err := c.RPC.R().SetContext(ctx).SetResult(res).SetError(res).SetQueryParams(queryParams).Get("/tx_search")
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to search txs")
+		return nil, api.NewUpstreamError(err, "failed to search txs")
 	}
 
 	if res.Error != nil {
 		if strings.Contains(res.Error.Data, "page should be within") {
 			return &coretypes.ResultTxSearch{Txs: []*coretypes.ResultTx{}, TotalCount: 0}, nil
 		}
-		return nil, errors.Wrap(errors.New(res.Error.Error()), "failed to search txs")
+		return nil, api.NewUpstreamError(errors.New(res.Error.Error()), "failed to search txs")
 	}
 
 	result := &coretypes.ResultTxSearch{}
@@ -81,10 +95,10 @@ func (c *HTTPClient) TxSearch(query string, page int, pageSize int) (*coretypes.
 	return result, nil
 }
 
-func (c *HTTPClient) BroadcastTx(rawTx string) (string, error) {
+func (c *HTTPClient) BroadcastTx(ctx context.Context, rawTx string) (string, error) {
 	txBytes, err := base64.StdEncoding.DecodeString(rawTx)
 	if err != nil {
-		return "", errors.Wrapf(err, "failed to decode rawTx: %s", rawTx)
+		return "", api.NewInvalidTxError(fmt.Sprintf("failed to decode rawTx: %v", err), nil)
 	}
 
 	var res struct {
@@ -104,14 +118,36 @@ func (c *HTTPClient) BroadcastTx(rawTx string) (string, error) {
 		} `json:"tx_response"`
 	}
 
-	_, err = c.LCD.R().SetBody(&txtypes.BroadcastTxRequest{TxBytes: txBytes, Mode: txtypes.BroadcastMode_BROADCAST_MODE_SYNC}).SetResult(&res).Post("/cosmos/tx/v1beta1/txs")
+	var e struct {
+		Code    uint32 `json:"code"`
+		Message string `json:"message"`
+	}
+
+	r, err := c.LCD.R().SetContext(ctx).SetBody(&txtypes.BroadcastTxRequest{TxBytes: txBytes, Mode: txtypes.BroadcastMode_BROADCAST_MODE_SYNC}).SetResult(&res).SetError(&e).Post("/cosmos/tx/v1beta1/txs")
 	if err != nil {
-		return "", errors.Wrap(err, "failed to broadcast transaction")
+		return "", api.NewUpstreamError(err, "failed to broadcast transaction")
+	}
+
+	if r.IsError() {
+		details := &api.ErrorDetails{Code: e.Code, Log: e.Message, Status: r.StatusCode()}
+
+		// the request is rejected before the tx is checked if the tx bytes can not be decoded
+		if r.StatusCode() < http.StatusInternalServerError {
+			return "", api.NewInvalidTxError("failed to broadcast transaction: invalid transaction", details)
+		}
+
+		return "", api.NewUpstreamError(errors.New(e.Message), "failed to broadcast transaction").WithDetails(details)
 	}
 
 	if res.TxResponse.Code != 0 {
-		message := fmt.Sprintf("failed to broadcast transaction: codespace: %s, code: %d, description", res.TxResponse.Codespace, res.TxResponse.Code)
-		return "", sdkerrors.ABCIError(res.TxResponse.Codespace, res.TxResponse.Code, message)
+		message := fmt.Sprintf("failed to broadcast transaction: codespace: %s, code: %d", res.TxResponse.Codespace, res.TxResponse.Code)
+		details := &api.ErrorDetails{Codespace: res.TxResponse.Codespace, Code: res.TxResponse.Code, Log: res.TxResponse.RawLog}
+
+		if res.TxResponse.Codespace == sdkerrors.RootCodespace && res.TxResponse.Code == sdkerrors.ErrTxDecode.ABCICode() {
+			return "", api.NewInvalidTxError(message, details)
+		}
+
+		return "", api.NewTxRejectedError(message, details)
 	}
 
 	return res.TxResponse.TxHash, nil
@@ -301,6 +337,79 @@ func ParseMessages(msgs []sdk.Msg, events cosmossdk.EventsByMsgIndex) []cosmossd
 	return messages
 }
 
+// GetTxFromBlockEvent creates a synthetic transaction for a staking lifecycle block event (unbonding or redelegation completion).
+// Any other block event is not supported and returns nil.
+func GetTxFromBlockEvent(blockHeader cometbfttypes.Header, blockEvent cosmossdk.ABCIEvent, eventIndex int, latestHeight int, denom string) (*cosmossdk.Tx, error) {
+	// other block events (ex. mint) have an amount in a different format, or none at all, so are skipped before parsing
+	switch blockEvent.Type {
+	case "complete_unbonding", "complete_redelegation":
+	default:
+		return nil, nil
+	}
+
+	attributes := make(cosmossdk.ValueByAttribute)
+	for _, a := range blockEvent.Attributes {
+		attributes[a.Key] = a.Value
+	}
+
+	// amount is empty if the completed entry has no remaining balance (ex. fully slashed)
+	coins, err := sdk.ParseCoinsNormalized(attributes["amount"])
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s amount: %s", blockEvent.Type, attributes["amount"])
+	}
+
+	value := cosmossdk.Value{Amount: "0", Denom: denom}
+	if len(coins) > 0 {
+		value = CoinToValue(&coins[0])
+	}
+
+	var message cosmossdk.Message
+	switch blockEvent.Type {
+	case "complete_unbonding":
+		message = cosmossdk.Message{
+			Addresses: []string{attributes["delegator"]},
+			Index:     "0",
+			Origin:    attributes["delegator"],
+			From:      attributes["validator"],
+			To:        attributes["delegator"],
+			Type:      "complete_unbonding",
+			Value:     value,
+		}
+	case "complete_redelegation":
+		message = cosmossdk.Message{
+			Addresses: []string{attributes["delegator"]},
+			Index:     "0",
+			Origin:    attributes["delegator"],
+			From:      attributes["source_validator"],
+			To:        attributes["destination_validator"],
+			Type:      "complete_redelegation",
+			Value:     value,
+		}
+	}
+
+	// block events are not part of any transaction, so derive a unique and deterministic txid from the event position within the block
+	txid := fmt.Sprintf("%X", sha256.Sum256([]byte(fmt.Sprintf("%d:%s:%d", blockHeader.Height, blockEvent.Type, eventIndex))))
+	blockHash := blockHeader.Hash().String()
+
+	tx := &cosmossdk.Tx{
+		BaseTx: api.BaseTx{
+			TxID:        txid,
+			BlockHash:   &blockHash,
+			BlockHeight: int(blockHeader.Height),
+			Timestamp:   int(blockHeader.Time.Unix()),
+		},
+		Confirmations: latestHeight - int(blockHeader.Height) + 1,
+		Events:        cosmossdk.EventsByMsgIndex{"0": cosmossdk.AttributesByEvent{blockEvent.Type: attributes}},
+		Fee:           cosmossdk.Value{Amount: "0", Denom: denom},
+		GasWanted:     "0",
+		GasUsed:       "0",
+		Index:         -1, // synthetic transactions don't have a real tx index
+		Messages:      []cosmossdk.Message{message},
+	}
+
+	return tx, nil
+}
+
 func Fee(tx SigningTx, txid string, denom string) cosmossdk.Value {
 	fees := tx.GetFee()
 
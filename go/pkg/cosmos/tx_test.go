@@ -0,0 +1,44 @@
+package cosmos
+
+import (
+	"testing"
+	"time"
+
+	cometbfttypes "github.com/cometbft/cometbft/types"
+	"github.com/shapeshift/unchained/shared/cosmossdk"
+)
+
+func TestGetTxFromBlockEvent(t *testing.T) {
+	header := cometbfttypes.Header{Height: 100, Time: time.Unix(1700000000, 0)}
+
+	// unsupported block events are skipped regardless of the format of their amount
+	mint := cosmossdk.ABCIEvent{Type: "mint", Attributes: []cosmossdk.ABCIEventAttribute{{Key: "amount", Value: "123456"}}}
+
+	tx, err := GetTxFromBlockEvent(header, mint, 0, 100, "uatom")
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	if tx != nil {
+		t.Fatalf("expected no tx for unsupported block event: %+v", tx)
+	}
+
+	unbonding := cosmossdk.ABCIEvent{Type: "complete_unbonding", Attributes: []cosmossdk.ABCIEventAttribute{
+		{Key: "amount", Value: "1000uatom"},
+		{Key: "validator", Value: "cosmosvaloper1"},
+		{Key: "delegator", Value: "cosmos1"},
+	}}
+
+	tx, err = GetTxFromBlockEvent(header, unbonding, 1, 100, "uatom")
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	if tx == nil || len(tx.Messages) != 1 {
+		t.Fatalf("expected tx with a single message: %+v", tx)
+	}
+
+	if value := tx.Messages[0].Value; value.Amount != "1000" || value.Denom != "uatom" {
+		t.Fatalf("unexpected value: %+v", value)
+	}
+}
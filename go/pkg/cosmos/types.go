@@ -1,6 +1,8 @@
 package cosmos
 
 import (
+	"context"
+
 	coretypes "github.com/cometbft/cometbft/rpc/core/types"
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/shapeshift/unchained/shared/cosmossdk"
@@ -16,7 +18,7 @@ func (r *ResultBlockResults) GetBlockEvents() []cosmossdk.ABCIEvent {
 
 type ResultTx struct {
 	*coretypes.ResultTx
-	formatTx func(tx *coretypes.ResultTx) (*cosmossdk.Tx, error)
+	formatTx func(ctx context.Context, tx *coretypes.ResultTx) (*cosmossdk.Tx, error)
 }
 
 func (r *ResultTx) GetHeight() int64 {
@@ -31,8 +33,8 @@ func (r *ResultTx) GetTxID() string {
 	return r.Hash.String()
 }
 
-func (r *ResultTx) FormatTx() (*cosmossdk.Tx, error) {
-	return r.formatTx(r.ResultTx)
+func (r *ResultTx) FormatTx(ctx context.Context) (*cosmossdk.Tx, error) {
+	return r.formatTx(ctx, r.ResultTx)
 }
 
 type SigningTx interface {
@@ -4,18 +4,21 @@ import (
 	"context"
 	"fmt"
 	"net"
-	"net/url"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"cosmossdk.io/simapp/params"
+	abci "github.com/cometbft/cometbft/abci/types"
 	cometbftjson "github.com/cometbft/cometbft/libs/json"
 	coretypes "github.com/cometbft/cometbft/rpc/core/types"
 	cometbft "github.com/cometbft/cometbft/rpc/jsonrpc/client"
 	"github.com/cometbft/cometbft/types"
 	"github.com/pkg/errors"
 	"github.com/shapeshift/unchained/shared/cosmossdk"
+	"github.com/shapeshift/unchained/shared/metrics"
 	"github.com/shapeshift/unchained/shared/websocket"
 )
 
@@ -24,40 +27,102 @@ const (
 	readWait     = 15 * time.Second
 	pingPeriod   = (readWait * 9) / 10
 	resetTimeout = 30 * time.Second
+	// max number of txs returned per request when backfilling missed blocks
+	backfillPageSize = 100
 )
 
 type TxHandlerFunc = func(tx types.EventDataTx, block *cosmossdk.BlockResponse) (interface{}, []string, error)
+type PendingTxHandlerFunc = func(rawTx []byte) (interface{}, []string, error)
+type BlockEventHandlerFunc = func(blockHeader types.Header, blockEvents []cosmossdk.ABCIEvent, eventIndex int) (interface{}, []string, error)
+
+// feed is a single upstream websocket connection, all of which are consumed concurrently for redundancy
+type feed struct {
+	name    string
+	client  *cometbft.WSClient
+	started bool
+	// set once subscribed to both txs and new blocks, cleared while resubscribing
+	subscribed atomic.Bool
+	t          *time.Timer
+}
 
 type WSClient struct {
 	*websocket.Registry
-	blockService *cosmossdk.BlockService
-	client       *cometbft.WSClient
-	encoding     *params.EncodingConfig
-	errChan      chan<- error
-	m            sync.RWMutex
-	t            *time.Timer
-	txHandler    TxHandlerFunc
-	unhandledTxs map[int][]types.EventDataTx
-}
-
-func NewWebsocketClient(conf cosmossdk.Config, blockService *cosmossdk.BlockService, errChan chan<- error) (*WSClient, error) {
-	wsURL, err := url.Parse(conf.WSURL)
+	blockEventHandler BlockEventHandlerFunc
+	blockService      *cosmossdk.BlockService
+	encoding          *params.EncodingConfig
+	errChan           chan<- error
+	feeds             []*feed
+	httpClient        *HTTPClient
+	ingest            bool
+	m                 sync.Mutex
+	mempool           *cosmossdk.MempoolWatcher
+	pendingTxHandler  PendingTxHandlerFunc
+	prometheus        *metrics.Prometheus
+	tracker           *cosmossdk.FeedTracker
+	txHandler         TxHandlerFunc
+	unhandledTxs      map[int][]types.EventDataTx
+}
+
+func NewWebsocketClient(conf cosmossdk.Config, httpClient *HTTPClient, blockService *cosmossdk.BlockService, errChan chan<- error, prometheus *metrics.Prometheus) (*WSClient, error) {
+	wsFeeds, err := conf.WSFeeds()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse websocket feeds")
+	}
+
+	brokerConf := websocket.BrokerConfigFromEnv()
+
+	broker, err := websocket.NewBroker(brokerConf)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create broker")
+	}
+
+	registry, err := websocket.NewRegistryWithBroker(broker)
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to parse WSURL: %s", conf.WSURL)
+		return nil, errors.Wrap(err, "failed to create registry")
 	}
 
+	ws := &WSClient{
+		Registry:     registry,
+		blockService: blockService,
+		encoding:     conf.Encoding.(*params.EncodingConfig),
+		errChan:      errChan,
+		httpClient:   httpClient,
+		ingest:       brokerConf.Ingest,
+		mempool:      cosmossdk.NewMempoolWatcher(httpClient, cosmossdk.MempoolPollIntervalFromEnv()),
+		prometheus:   prometheus,
+		tracker:      cosmossdk.NewFeedTracker(),
+		unhandledTxs: make(map[int][]types.EventDataTx),
+	}
+
+	cometbft.ReadWait(readWait)
+	cometbft.WriteWait(writeWait)
+	cometbft.PingPeriod(pingPeriod)
+
+	for _, wsFeed := range wsFeeds {
+		f, err := ws.newFeed(wsFeed)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create websocket feed: %s", wsFeed.Name)
+		}
+
+		ws.feeds = append(ws.feeds, f)
+	}
+
+	return ws, nil
+}
+
+func (ws *WSClient) newFeed(wsFeed cosmossdk.WSFeed) (*feed, error) {
 	endpoint := "/websocket"
-	if conf.WSAPIKEY != "" {
+	if wsFeed.APIKEY != "" {
 		endpoint = "/wss"
 	}
 
-	client, err := cometbft.NewWS(wsURL.String(), endpoint)
+	client, err := cometbft.NewWS(wsFeed.URL.String(), endpoint)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to create websocket client")
 	}
 
-	if conf.WSAPIKEY != "" {
-		username, password, ok := strings.Cut(conf.WSAPIKEY, ":")
+	if wsFeed.APIKEY != "" {
+		username, password, ok := strings.Cut(wsFeed.APIKEY, ":")
 		if !ok {
 			return nil, errors.New("invalid WSAPIKEY format: expected 'username:password'")
 		}
@@ -68,48 +133,71 @@ func NewWebsocketClient(conf cosmossdk.Config, blockService *cosmossdk.BlockServ
 	// use default dialer
 	client.Dialer = net.Dial
 
-	ws := &WSClient{
-		Registry:     websocket.NewRegistry(),
-		blockService: blockService,
-		client:       client,
-		encoding:     conf.Encoding.(*params.EncodingConfig),
-		errChan:      errChan,
-		unhandledTxs: make(map[int][]types.EventDataTx),
-	}
+	f := &feed{name: wsFeed.Name, client: client}
 
-	cometbft.ReadWait(readWait)
-	cometbft.WriteWait(writeWait)
-	cometbft.PingPeriod(pingPeriod)
 	cometbft.MaxReconnectAttempts(10)(client)
 	cometbft.OnReconnect(func() {
-		logger.Info("OnReconnect triggered: resubscribing")
-		ws.m.Lock()
-		ws.unhandledTxs = make(map[int][]types.EventDataTx)
-		ws.m.Unlock()
-		_ = client.Subscribe(context.Background(), types.EventQueryTx.String())
-		_ = client.Subscribe(context.Background(), types.EventQueryNewBlock.String())
+		logger.Infof("OnReconnect triggered: resubscribing feed: %s", f.name)
+		// any blocks committed while disconnected from all feeds are backfilled once the next new block is received
+		errTx := client.Subscribe(context.Background(), types.EventQueryTx.String())
+		errBlock := client.Subscribe(context.Background(), types.EventQueryNewBlock.String())
+		f.subscribed.Store(errTx == nil && errBlock == nil)
 	})(client)
 
-	return ws, nil
+	return f, nil
 }
 
 func (ws *WSClient) Start() error {
-	if err := ws.client.Start(); err != nil {
-		return errors.Wrap(err, "failed to start websocket client")
+	// receive txs and blocks from the broker instead of subscribing to the upstream node
+	if !ws.ingest {
+		ws.SubscribeTopic("blockService", "latest", websocket.TopicBlocks, cosmossdk.NewBlockSubscriber(ws.blockService))
+		return nil
+	}
+
+	// continue with any feeds available as long as at least one feed started successfully
+	for _, f := range ws.feeds {
+		if err := f.client.Start(); err != nil {
+			logger.Errorf("failed to start websocket feed: %s: %v", f.name, err)
+			continue
+		}
+
+		f.started = true
+
+		if err := ws.subscribe(f); err != nil {
+			logger.Errorf("failed to subscribe to websocket feed: %s: %v", f.name, err)
+		}
+
+		go ws.listen(f)
 	}
 
-	if err := ws.subscribe(); err != nil {
-		return errors.Wrap(err, "failed to subscribe")
+	if !ws.hasStartedFeed() {
+		return errors.New("failed to start any websocket feeds")
 	}
 
-	go ws.listen()
+	if ws.pendingTxHandler != nil {
+		ws.mempool.Start(ws.handlePendingTx)
+	}
 
 	return nil
 }
 
 func (ws *WSClient) Stop() {
-	if err := ws.client.Stop(); err != nil {
-		logger.Errorf("failed to stop the websocket client: %v", err)
+	if ws.ingest {
+		ws.mempool.Stop()
+
+		for _, f := range ws.feeds {
+			if !f.started {
+				continue
+			}
+
+			if err := f.client.Stop(); err != nil {
+				logger.Errorf("failed to stop websocket feed: %s: %v", f.name, err)
+			}
+		}
+	}
+
+	if err := ws.Registry.Close(); err != nil {
+		logger.Errorf("failed to close the registry: %v", err)
 	}
 }
 
@@ -117,65 +205,127 @@ func (ws *WSClient) TxHandler(fn TxHandlerFunc) {
 	ws.txHandler = fn
 }
 
+// PendingTxHandler sets the handler for txs detected in the mempool before they are confirmed
+func (ws *WSClient) PendingTxHandler(fn PendingTxHandlerFunc) {
+	ws.pendingTxHandler = fn
+}
+
+// BlockEventHandler sets the handler for events emitted by the block itself instead of a tx (ex. staking lifecycle completions)
+func (ws *WSClient) BlockEventHandler(fn BlockEventHandlerFunc) {
+	ws.blockEventHandler = fn
+}
+
 func (ws *WSClient) EncodingConfig() params.EncodingConfig {
 	return *ws.encoding
 }
 
-func (ws *WSClient) subscribe() error {
-	if err := ws.client.Subscribe(context.Background(), types.EventQueryTx.String()); err != nil {
+func (ws *WSClient) hasStartedFeed() bool {
+	for _, f := range ws.feeds {
+		if f.started {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CheckSubscriptions verifies at least one upstream websocket feed is connected and subscribed.
+// Api replicas receiving blocks from the broker have no upstream subscriptions and rely on the latest block check instead.
+func (ws *WSClient) CheckSubscriptions(ctx context.Context) error {
+	if !ws.ingest {
+		return nil
+	}
+
+	states := make([]string, 0, len(ws.feeds))
+	for _, f := range ws.feeds {
+		switch {
+		case !f.started:
+			states = append(states, fmt.Sprintf("%s: not started", f.name))
+		case !f.client.IsActive():
+			states = append(states, fmt.Sprintf("%s: disconnected", f.name))
+		case !f.subscribed.Load():
+			states = append(states, fmt.Sprintf("%s: not subscribed", f.name))
+		default:
+			return nil
+		}
+	}
+
+	return errors.Errorf("no websocket feeds subscribed: %s", strings.Join(states, ", "))
+}
+
+func (ws *WSClient) subscribe(f *feed) error {
+	// resubscribe after the reset timeout if subscribing fails or no events are received
+	f.t = time.AfterFunc(resetTimeout, func() { ws.reset(f) })
+
+	f.subscribed.Store(false)
+
+	if err := f.client.Subscribe(context.Background(), types.EventQueryTx.String()); err != nil {
 		return errors.Wrap(err, "failed to subscribe to txs")
 	}
 
-	if err := ws.client.Subscribe(context.Background(), types.EventQueryNewBlock.String()); err != nil {
+	if err := f.client.Subscribe(context.Background(), types.EventQueryNewBlock.String()); err != nil {
 		return errors.Wrap(err, "failed to subscribe to newBlocks")
 	}
 
-	ws.t = time.AfterFunc(resetTimeout, ws.reset)
+	f.subscribed.Store(true)
 
 	return nil
 }
 
-func (ws *WSClient) reset() {
-	logger.Debugln("reset websocket")
+func (ws *WSClient) reset(f *feed) {
+	logger.Debugf("reset websocket feed: %s", f.name)
 
-	ws.t.Stop()
+	f.t.Stop()
 
-	if err := ws.client.UnsubscribeAll(context.Background()); err != nil {
-		logger.Error(errors.Wrap(err, "failed to unsubscribe from all subscriptions"))
+	if err := f.client.UnsubscribeAll(context.Background()); err != nil {
+		logger.Error(errors.Wrapf(err, "failed to unsubscribe from all subscriptions: %s", f.name))
 	}
 
-	if err := ws.subscribe(); err != nil {
-		logger.Error(errors.Wrap(err, "failed to reset websocket"))
+	if err := ws.subscribe(f); err != nil {
+		logger.Error(errors.Wrapf(err, "failed to reset websocket feed: %s", f.name))
 	}
 }
 
-func (ws *WSClient) listen() {
-	for r := range ws.client.ResponsesCh {
+func (ws *WSClient) listen(f *feed) {
+	for r := range f.client.ResponsesCh {
 		if r.Error != nil {
 			// resubscribe if subscription is cancelled by the server for reason: client is not pulling messages fast enough
 			if r.Error.Code == -32000 {
-				ws.reset()
+				ws.reset(f)
 				continue
 			}
 
-			logger.Error(r.Error.Error())
+			logger.Errorf("%s: %s", f.name, r.Error.Error())
 			continue
 		}
 
 		result := &coretypes.ResultEvent{}
 		if err := cometbftjson.Unmarshal(r.Result, result); err != nil {
-			logger.Errorf("failed to unmarshal result event: %v", err)
+			logger.Errorf("failed to unmarshal result event: %s: %v", f.name, err)
 			continue
 		}
 
 		if result.Data != nil {
 			switch result.Data.(type) {
 			case types.EventDataTx:
-				ws.t.Reset(resetTimeout)
-				go ws.handleTx(result.Data.(types.EventDataTx))
+				f.t.Reset(resetTimeout)
+				tx := result.Data.(types.EventDataTx)
+				if ws.tracker.IsStale(int(tx.Height)) || !ws.tracker.TrackTx(int(tx.Height), tx.Tx) {
+					continue
+				}
+				go ws.handleTx(tx)
 			case types.EventDataNewBlock:
-				ws.t.Reset(resetTimeout)
-				go ws.handleNewBlock(result.Data.(types.EventDataNewBlock))
+				f.t.Reset(resetTimeout)
+				newBlock := result.Data.(types.EventDataNewBlock)
+				first, gap, from, to := ws.trackHeight(int(newBlock.Block.Height))
+				if !first {
+					continue
+				}
+				ws.prometheus.Metrics.WebsocketFeedFirstBlocks.With(metrics.Labels{"feed": f.name}).Inc()
+				if gap {
+					go cosmossdk.Backfill(context.Background(), from, to, ws.backfillBlock)
+				}
+				go ws.handleNewBlock(newBlock)
 			default:
 				fmt.Printf("unsupported result type: %T", result.Data)
 			}
@@ -184,6 +334,9 @@ func (ws *WSClient) listen() {
 }
 
 func (ws *WSClient) handleTx(tx types.EventDataTx) {
+	// prevent a confirmed tx from being detected as pending by any in flight mempool poll
+	ws.mempool.Confirm(tx.Tx)
+
 	// queue up any transactions detected before block details are available
 	block, ok := ws.blockService.ReadBlock(int(tx.Height))
 	if !ok {
@@ -204,6 +357,16 @@ func (ws *WSClient) handleTx(tx types.EventDataTx) {
 	}
 }
 
+func (ws *WSClient) handlePendingTx(rawTx []byte) {
+	data, addrs, err := ws.pendingTxHandler(rawTx)
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+
+	ws.Publish(addrs, data)
+}
+
 func (ws *WSClient) handleNewBlock(newBlock types.EventDataNewBlock) {
 	b := &cosmossdk.BlockResponse{
 		Height:    int(newBlock.Block.Height),
@@ -213,6 +376,8 @@ func (ws *WSClient) handleNewBlock(newBlock types.EventDataNewBlock) {
 
 	ws.blockService.WriteBlock(b, true)
 
+	ws.PublishTopic(websocket.TopicBlocks, cosmossdk.Block{Height: b.Height, Hash: b.Hash, Timestamp: b.Timestamp})
+
 	// process any unhandled transactions
 	ws.m.Lock()
 	unhandledTxs := ws.unhandledTxs[b.Height]
@@ -222,4 +387,111 @@ func (ws *WSClient) handleNewBlock(newBlock types.EventDataNewBlock) {
 	for _, tx := range unhandledTxs {
 		go ws.handleTx(tx)
 	}
+
+	if ws.blockEventHandler != nil {
+		go ws.handleBlockEvents(newBlock.Block.Header, ConvertABCIEvents(newBlock.ResultFinalizeBlock.Events))
+	}
+}
+
+func (ws *WSClient) handleBlockEvents(header types.Header, blockEvents []cosmossdk.ABCIEvent) {
+	for i := range blockEvents {
+		data, addrs, err := ws.blockEventHandler(header, blockEvents, i)
+		if err != nil {
+			logger.Error(err)
+			continue
+		}
+
+		if data != nil {
+			ws.Publish(addrs, data)
+		}
+	}
+}
+
+// trackHeight records the height of a new block received from any feed, returning whether the block is the first delivery
+// of its height along with the range of any heights missed since the previous new block.
+// Any txs still queued for blocks outside of the dedupe window that were never received are discarded.
+func (ws *WSClient) trackHeight(height int) (first bool, gap bool, from int, to int) {
+	first, gap, from, to = ws.tracker.TrackHeight(height)
+	if !first {
+		return false, false, 0, 0
+	}
+
+	ws.m.Lock()
+	for h := range ws.unhandledTxs {
+		if ws.tracker.IsStale(h) {
+			delete(ws.unhandledTxs, h)
+		}
+	}
+	ws.m.Unlock()
+
+	return first, gap, from, to
+}
+
+// backfillBlock publishes the txs of a missed block through the same handlers as txs received from the websocket.
+// All block details are fetched before publishing so a failed attempt can be retried without publishing duplicates.
+func (ws *WSClient) backfillBlock(ctx context.Context, height int) error {
+	result, err := ws.httpClient.Block(ctx, &height)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get block: %d", height)
+	}
+
+	b := &cosmossdk.BlockResponse{
+		Height:    int(result.Block.Height),
+		Hash:      result.Block.Hash().String(),
+		Timestamp: int(result.Block.Time.Unix()),
+	}
+
+	txs := []types.EventDataTx{}
+	for page := 1; ; page++ {
+		res, err := ws.httpClient.TxSearch(ctx, fmt.Sprintf("tx.height=%d", height), page, backfillPageSize)
+		if err != nil {
+			return errors.Wrapf(err, "failed to search txs for block: %d", height)
+		}
+
+		for _, tx := range res.Txs {
+			txs = append(txs, types.EventDataTx{TxResult: abci.TxResult{Height: tx.Height, Index: tx.Index, Tx: tx.Tx, Result: tx.TxResult}})
+		}
+
+		if len(res.Txs) == 0 || len(txs) >= res.TotalCount {
+			break
+		}
+	}
+
+	// block events are not included in tx results
+	var blockEvents []cosmossdk.ABCIEvent
+	if ws.blockEventHandler != nil {
+		blockResults, err := ws.httpClient.BlockResults(ctx, height)
+		if err != nil {
+			return errors.Wrapf(err, "failed to get block results: %d", height)
+		}
+
+		blockEvents = blockResults.GetBlockEvents()
+	}
+
+	sort.Slice(txs, func(i, j int) bool { return txs[i].Index < txs[j].Index })
+
+	ws.blockService.WriteBlock(b, false)
+
+	// process any txs received from a feed while waiting for the missed block
+	ws.m.Lock()
+	unhandledTxs := ws.unhandledTxs[height]
+	delete(ws.unhandledTxs, height)
+	ws.m.Unlock()
+
+	for _, tx := range unhandledTxs {
+		ws.handleTx(tx)
+	}
+
+	// skip any txs of the missed block already received from a feed
+	for _, tx := range txs {
+		if ws.tracker.TrackTx(int(tx.Height), tx.Tx) {
+			ws.handleTx(tx)
+		}
+	}
+
+	if ws.blockEventHandler != nil {
+		ws.handleBlockEvents(result.Block.Header, blockEvents)
+	}
+
+	return nil
 }
@@ -0,0 +1,36 @@
+package cosmos
+
+import (
+	"testing"
+
+	"github.com/cometbft/cometbft/types"
+	"github.com/shapeshift/unchained/shared/cosmossdk"
+)
+
+func TestTrackHeightDiscardsStaleTxs(t *testing.T) {
+	ws := &WSClient{
+		tracker: cosmossdk.NewFeedTracker(),
+		unhandledTxs: map[int][]types.EventDataTx{
+			50:  {{}},
+			150: {{}},
+		},
+	}
+
+	if first, gap, _, _ := ws.trackHeight(150); !first || gap {
+		t.Fatalf("expected first delivery of block without gap, got first: %t, gap: %t", first, gap)
+	}
+
+	// txs queued for blocks outside of the dedupe window are discarded as the block will never be received
+	if _, ok := ws.unhandledTxs[50]; ok {
+		t.Error("expected stale txs to be discarded")
+	}
+
+	if _, ok := ws.unhandledTxs[150]; !ok {
+		t.Error("expected txs within the dedupe window to be kept")
+	}
+
+	// a block already received from another feed is not handled again
+	if first, _, _, _ := ws.trackHeight(150); first {
+		t.Error("expected duplicate block not to be the first delivery")
+	}
+}
@@ -1,6 +1,7 @@
 package mayachain
 
 import (
+	"context"
 	"strconv"
 	"strings"
 
@@ -11,7 +12,23 @@ import (
 	rpctypes "github.com/tendermint/tendermint/rpc/jsonrpc/types"
 )
 
-func (c *HTTPClient) GetBlock(height *int) (*cosmossdk.ResultBlock, error) {
+func (c *HTTPClient) GetBlock(ctx context.Context, height *int) (*cosmossdk.ResultBlock, error) {
+	result, err := c.Block(ctx, height)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &cosmossdk.ResultBlock{
+		Height: result.Block.Height,
+		Time:   result.Block.Time,
+		Hash:   result.Block.Hash().String(),
+	}
+
+	return b, nil
+}
+
+// Block returns the full block at the height provided or the latest block if nil
+func (c *HTTPClient) Block(ctx context.Context, height *int) (*coretypes.ResultBlock, error) {
 	res := &rpctypes.RPCResponse{}
 
 	hs := ""
@@ -19,9 +36,9 @@ func (c *HTTPClient) GetBlock(height *int) (*cosmossdk.ResultBlock, error) {
 		hs = strconv.Itoa(*height)
 	}
 
-	_, err := c.RPC.R().SetResult(res).SetError(res).SetQueryParam("height", hs).Get("/block")
+	_, err := c.RPC.R().SetContext(ctx).SetResult(res).SetError(res).SetQueryParam("height", hs).Get("/block")
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to get block: %d", height)
+		return nil, errors.Wrapf(err, "failed to get block: %s", hs)
 	}
 
 	if res.Error != nil {
@@ -30,19 +47,13 @@ func (c *HTTPClient) GetBlock(height *int) (*cosmossdk.ResultBlock, error) {
 
 	result := &coretypes.ResultBlock{}
 	if err := tendermintjson.Unmarshal(res.Result, result); err != nil {
-		return nil, errors.Errorf("failed to unmarshal block result: %v: %s", res.Result, res.Error.Error())
-	}
-
-	b := &cosmossdk.ResultBlock{
-		Height: result.Block.Height,
-		Time:   result.Block.Time,
-		Hash:   result.Block.Hash().String(),
+		return nil, errors.Errorf("failed to unmarshal block result: %v", res.Result)
 	}
 
-	return b, nil
+	return result, nil
 }
 
-func (c *HTTPClient) BlockSearch(query string, page int, pageSize int) (*coretypes.ResultBlockSearch, error) {
+func (c *HTTPClient) BlockSearch(ctx context.Context, query string, page int, pageSize int) (*coretypes.ResultBlockSearch, error) {
 	res := &rpctypes.RPCResponse{}
 
 	queryParams := map[string]string{
@@ -52,7 +63,7 @@ func (c *HTTPClient) BlockSearch(query string, page int, pageSize int) (*coretyp
 		"order_by": "\"desc\"",
 	}
 
-	_, err := c.RPC.R().SetResult(res).SetError(res).SetQueryParams(queryParams).Get("/block_search")
+	_, err := c.RPC.R().SetContext(ctx).SetResult(res).SetError(res).SetQueryParams(queryParams).Get("/block_search")
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to search blocks")
 	}
@@ -72,10 +83,10 @@ func (c *HTTPClient) BlockSearch(query string, page int, pageSize int) (*coretyp
 	return result, nil
 }
 
-func (c *HTTPClient) BlockResults(height int) (cosmossdk.BlockResults, error) {
+func (c *HTTPClient) BlockResults(ctx context.Context, height int) (cosmossdk.BlockResults, error) {
 	res := &rpctypes.RPCResponse{}
 
-	_, err := c.RPC.R().SetResult(res).SetError(res).SetQueryParam("height", strconv.Itoa(height)).Get("/block_results")
+	_, err := c.RPC.R().SetContext(ctx).SetResult(res).SetError(res).SetQueryParam("height", strconv.Itoa(height)).Get("/block_results")
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to get block results for block: %v", height)
 	}
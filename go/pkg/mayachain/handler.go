@@ -1,10 +1,13 @@
 package mayachain
 
 import (
+	"context"
 	"crypto/sha256"
 	"fmt"
+	"net/http"
 	"reflect"
 	"strconv"
+	"time"
 
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	ws "github.com/gorilla/websocket"
@@ -55,12 +58,26 @@ func (h *Handler) ValidateCoinSpecific(handler interface{}) error {
 
 func (h *Handler) NewWebsocketConnection(conn *ws.Conn, manager *websocket.Manager) {
 	c := websocket.NewConnection(conn, h.WSClient, manager)
+	c.AddressValidator(IsValidAddress)
+	c.ReplayHandler(cosmossdk.NewReplayHandler(func(ctx context.Context, pubkey string, cursor string, pageSize int) (api.TxHistory, error) {
+		return GetTxHistory(ctx, h, pubkey, cursor, pageSize)
+	}))
 	c.Start()
 }
 
+func (h *Handler) NewStreamConnection(w http.ResponseWriter, r *http.Request, pubkey string, manager *websocket.Manager) {
+	s := websocket.NewStream(h.WSClient, manager)
+	s.ReplayHandler(cosmossdk.NewReplayHandler(func(ctx context.Context, pubkey string, cursor string, pageSize int) (api.TxHistory, error) {
+		return GetTxHistory(ctx, h, pubkey, cursor, pageSize)
+	}))
+	s.Serve(w, r, pubkey)
+}
+
 func (h *Handler) StartWebsocket() error {
+	h.WSClient.BalanceHandler(cosmossdk.NewBalanceHandler(h.HTTPClient.GetBalance, h.Denom))
+
 	h.WSClient.BlockEventHandler(func(eventCache map[string]interface{}, blockHeader types.Header, blockEvents []cosmossdk.ABCIEvent, eventIndex int) (interface{}, []string, error) {
-		tx, err := GetTxFromBlockEvents(eventCache, blockHeader, blockEvents, eventIndex, h.BlockService.Latest.Height, h.Denom, h.NativeFee)
+		tx, err := GetTxFromBlockEvents(eventCache, blockHeader, blockEvents, eventIndex, h.BlockService.LatestHeight(), h.Denom, h.NativeFee)
 		if err != nil {
 			return nil, nil, errors.Wrap(err, "failed to get txs from end block events")
 		}
@@ -69,7 +86,7 @@ func (h *Handler) StartWebsocket() error {
 			return nil, nil, nil
 		}
 
-		t, err := tx.FormatTx()
+		t, err := tx.FormatTx(context.Background())
 		if err != nil {
 			return nil, nil, errors.Wrapf(err, "failed to format transaction: %s", tx.TxID)
 		}
@@ -110,6 +127,43 @@ func (h *Handler) StartWebsocket() error {
 		return t, addrs, nil
 	})
 
+	h.WSClient.PendingTxHandler(func(rawTx []byte) (interface{}, []string, error) {
+		decodedTx, signingTx, err := DecodeTx(h.WSClient.EncodingConfig(), rawTx)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to handle pending tx: %v", rawTx)
+		}
+
+		txid := cosmossdk.TxID(rawTx)
+
+		// no events are emitted until the tx is executed
+		events := cosmossdk.EventsByMsgIndex{}
+
+		gasWanted := "0"
+		if feeTx, ok := decodedTx.(sdk.FeeTx); ok {
+			gasWanted = strconv.FormatUint(feeTx.GetGas(), 10)
+		}
+
+		t := cosmossdk.Tx{
+			BaseTx: api.BaseTx{
+				TxID:        txid,
+				BlockHeight: -1,
+				Timestamp:   int(time.Now().Unix()),
+			},
+			Confirmations: 0,
+			Events:        events,
+			Fee:           h.ParseFee(signingTx, txid),
+			GasWanted:     gasWanted,
+			GasUsed:       "0",
+			Index:         -1,
+			Memo:          signingTx.GetMemo(),
+			Messages:      h.ParseMessages(decodedTx.GetMsgs(), events),
+		}
+
+		addrs := cosmossdk.GetTxAddrs(t.Events, t.Messages)
+
+		return t, addrs, nil
+	})
+
 	err := h.WSClient.Start()
 	if err != nil {
 		return errors.WithStack(err)
@@ -122,10 +176,15 @@ func (h *Handler) StopWebsocket() {
 	h.WSClient.Stop()
 }
 
-func (h *Handler) GetTxHistory(pubkey string, cursor string, pageSize int) (api.TxHistory, error) {
+// HealthChecks returns the readiness checks of the upstream nodes, latest block and websocket subscriptions
+func (h *Handler) HealthChecks() []api.HealthCheck {
+	return append(h.Handler.HealthChecks(), api.HealthCheck{Name: "websocket", Check: h.WSClient.CheckSubscriptions})
+}
+
+func (h *Handler) GetTxHistory(ctx context.Context, pubkey string, cursor string, pageSize int) (api.TxHistory, error) {
 	sources := TxHistorySources(h.HTTPClient, pubkey, h.FormatTx)
 
-	res, err := h.HTTPClient.GetTxHistory(pubkey, cursor, pageSize, sources)
+	res, err := h.HTTPClient.GetTxHistory(ctx, pubkey, cursor, pageSize, sources)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to get tx history")
 	}
@@ -143,13 +202,13 @@ func (h *Handler) GetTxHistory(pubkey string, cursor string, pageSize int) (api.
 	return txHistory, nil
 }
 
-func (h *Handler) GetTx(txid string) (api.Tx, error) {
-	tx, err := h.HTTPClient.GetTx(txid)
+func (h *Handler) GetTx(ctx context.Context, txid string) (api.Tx, error) {
+	tx, err := h.HTTPClient.GetTx(ctx, txid)
 	if err != nil {
 		return nil, err
 	}
 
-	t, err := h.FormatTx(tx)
+	t, err := h.FormatTx(ctx, tx)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to format transaction: %s", tx.Hash)
 	}
@@ -157,10 +216,10 @@ func (h *Handler) GetTx(txid string) (api.Tx, error) {
 	return t, nil
 }
 
-func (h *Handler) FormatTx(tx *coretypes.ResultTx) (*cosmossdk.Tx, error) {
+func (h *Handler) FormatTx(ctx context.Context, tx *coretypes.ResultTx) (*cosmossdk.Tx, error) {
 	height := int(tx.Height)
 
-	block, err := h.BlockService.GetBlock(height)
+	block, err := h.BlockService.GetBlock(ctx, height)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to get block: %d", height)
 	}
@@ -179,7 +238,7 @@ func (h *Handler) FormatTx(tx *coretypes.ResultTx) (*cosmossdk.Tx, error) {
 			BlockHeight: block.Height,
 			Timestamp:   block.Timestamp,
 		},
-		Confirmations: h.BlockService.Latest.Height - height + 1,
+		Confirmations: h.BlockService.LatestHeight() - height + 1,
 		Events:        events,
 		Fee:           h.ParseFee(signingTx, tx.Hash.String()),
 		GasWanted:     strconv.Itoa(int(tx.TxResult.GasWanted)),
@@ -1,6 +1,7 @@
 package mayachain
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/pkg/errors"
@@ -8,9 +9,9 @@ import (
 	coretypes "github.com/tendermint/tendermint/rpc/core/types"
 )
 
-func TxHistorySources(client APIClient, pubkey string, formatTx func(*coretypes.ResultTx) (*cosmossdk.Tx, error)) map[string]*cosmossdk.TxState {
-	request := func(query string, page int, pageSize int) ([]cosmossdk.HistoryTx, error) {
-		result, err := client.TxSearch(query, page, pageSize)
+func TxHistorySources(client APIClient, pubkey string, formatTx func(context.Context, *coretypes.ResultTx) (*cosmossdk.Tx, error)) map[string]*cosmossdk.TxState {
+	request := func(ctx context.Context, query string, page int, pageSize int) ([]cosmossdk.HistoryTx, error) {
+		result, err := client.TxSearch(ctx, query, page, pageSize)
 		if err != nil {
 			return nil, errors.WithStack(err)
 		}
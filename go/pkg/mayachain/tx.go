@@ -1,8 +1,10 @@
 package mayachain
 
 import (
+	"context"
 	"encoding/base64"
 	"fmt"
+	"net/http"
 	"strconv"
 	"strings"
 
@@ -24,20 +26,30 @@ import (
 	mayatypes "gitlab.com/mayachain/mayanode/x/mayachain/types"
 )
 
-func (c *HTTPClient) GetTx(txid string) (*coretypes.ResultTx, error) {
+func (c *HTTPClient) GetTx(ctx context.Context, txid string) (*coretypes.ResultTx, error) {
 	res := &rpctypes.RPCResponse{}
 
 	if !strings.HasPrefix(txid, "0x") {
 		txid = "0x" + txid
 	}
 
-	_, err := c.RPC.R().SetResult(res).SetError(res).SetQueryParam("hash", txid).Get("/tx")
+	_, err := c.RPC.R().SetContext(ctx).SetResult(res).SetError(res).SetQueryParam("hash", txid).Get("/tx")
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to get tx: %s", txid)
+		return nil, api.NewUpstreamError(err, fmt.Sprintf("failed to get tx: %s", txid))
 	}
 
 	if res.Error != nil {
-		return nil, errors.Wrapf(errors.New(res.Error.Error()), "failed to get tx: %s", txid)
+		// rpc responds with a generic internal error if the tx does not exist, so confirm with the lcd node instead
+		exists, err := c.TxExists(ctx, txid)
+		if err != nil {
+			return nil, err
+		}
+
+		if !exists {
+			return nil, api.NewTxNotFoundError(strings.TrimPrefix(txid, "0x"))
+		}
+
+		return nil, api.NewUpstreamError(errors.New(res.Error.Error()), fmt.Sprintf("failed to get tx: %s", txid))
 	}
 
 	tx := &coretypes.ResultTx{}
@@ -48,7 +60,7 @@ func (c *HTTPClient) GetTx(txid string) (*coretypes.ResultTx, error) {
 	return tx, nil
 }
 
-func (c *HTTPClient) TxSearch(query string, page int, pageSize int) (*coretypes.ResultTxSearch, error) {
+func (c *HTTPClient) TxSearch(ctx context.Context, query string, page int, pageSize int) (*coretypes.ResultTxSearch, error) {
 	res := &rpctypes.RPCResponse{}
 
 	queryParams := map[string]string{
@@ -58,16 +70,16 @@ func (c *HTTPClient) TxSearch(query string, page int, pageSize int) (*coretypes.
 		"order_by": "\"desc\"",
 	}
 
-	_, err := c.RPC.R().SetResult(res).SetError(res).SetQueryParams(queryParams).Get("/tx_search")
+	_, err := c.RPC.R().SetContext(ctx).SetResult(res).SetError(res).SetQueryParams(queryParams).Get("/tx_search")
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to search txs")
+		return nil, api.NewUpstreamError(err, "failed to search txs")
 	}
 
 	if res.Error != nil {
 		if strings.Contains(res.Error.Data, "page should be within") {
 			return &coretypes.ResultTxSearch{Txs: []*coretypes.ResultTx{}, TotalCount: 0}, nil
 		}
-		return nil, errors.Wrap(errors.New(res.Error.Error()), "failed to search txs")
+		return nil, api.NewUpstreamError(errors.New(res.Error.Error()), "failed to search txs")
 	}
 
 	result := &coretypes.ResultTxSearch{}
@@ -78,10 +90,10 @@ func (c *HTTPClient) TxSearch(query string, page int, pageSize int) (*coretypes.
 	return result, nil
 }
 
-func (c *HTTPClient) BroadcastTx(rawTx string) (string, error) {
+func (c *HTTPClient) BroadcastTx(ctx context.Context, rawTx string) (string, error) {
 	txBytes, err := base64.StdEncoding.DecodeString(rawTx)
 	if err != nil {
-		return "", errors.Wrapf(err, "failed to decode rawTx: %s", rawTx)
+		return "", api.NewInvalidTxError(fmt.Sprintf("failed to decode rawTx: %v", err), nil)
 	}
 
 	var res struct {
@@ -101,31 +113,58 @@ func (c *HTTPClient) BroadcastTx(rawTx string) (string, error) {
 		} `json:"tx_response"`
 	}
 
-	_, err = c.LCD.R().SetBody(&txtypes.BroadcastTxRequest{TxBytes: txBytes, Mode: txtypes.BroadcastMode_BROADCAST_MODE_SYNC}).SetResult(&res).Post("/cosmos/tx/v1beta1/txs")
+	var e struct {
+		Code    uint32 `json:"code"`
+		Message string `json:"message"`
+	}
+
+	r, err := c.LCD.R().SetContext(ctx).SetBody(&txtypes.BroadcastTxRequest{TxBytes: txBytes, Mode: txtypes.BroadcastMode_BROADCAST_MODE_SYNC}).SetResult(&res).SetError(&e).Post("/cosmos/tx/v1beta1/txs")
 	if err != nil {
-		return "", errors.Wrap(err, "failed to broadcast transaction")
+		return "", api.NewUpstreamError(err, "failed to broadcast transaction")
+	}
+
+	if r.IsError() {
+		details := &api.ErrorDetails{Code: e.Code, Log: e.Message, Status: r.StatusCode()}
+
+		// the request is rejected before the tx is checked if the tx bytes can not be decoded
+		if r.StatusCode() < http.StatusInternalServerError {
+			return "", api.NewInvalidTxError("failed to broadcast transaction: invalid transaction", details)
+		}
+
+		return "", api.NewUpstreamError(errors.New(e.Message), "failed to broadcast transaction").WithDetails(details)
 	}
 
 	if res.TxResponse.Code != 0 {
-		message := fmt.Sprintf("failed to broadcast transaction: codespace: %s, code: %d, description", res.TxResponse.Codespace, res.TxResponse.Code)
-		return "", sdkerrors.ABCIError(res.TxResponse.Codespace, res.TxResponse.Code, message)
+		message := fmt.Sprintf("failed to broadcast transaction: codespace: %s, code: %d", res.TxResponse.Codespace, res.TxResponse.Code)
+		details := &api.ErrorDetails{Codespace: res.TxResponse.Codespace, Code: res.TxResponse.Code, Log: res.TxResponse.RawLog}
+
+		if res.TxResponse.Codespace == sdkerrors.RootCodespace && res.TxResponse.Code == sdkerrors.ErrTxDecode.ABCICode() {
+			return "", api.NewInvalidTxError(message, details)
+		}
+
+		return "", api.NewTxRejectedError(message, details)
 	}
 
 	return res.TxResponse.TxHash, nil
 }
 
-func GetTxHistory(handler *Handler, pubkey string, cursor string, pageSize int) (api.TxHistory, error) {
-	request := func(query string, page int, pageSize int) ([]cosmossdk.HistoryTx, error) {
+func GetTxHistory(ctx context.Context, handler *Handler, pubkey string, cursor string, pageSize int) (api.TxHistory, error) {
+	request := func(ctx context.Context, query string, page int, pageSize int) ([]cosmossdk.HistoryTx, error) {
 		// search for any blocks where pubkey was associated with an indexed block event
-		result, err := handler.HTTPClient.BlockSearch(query, page, pageSize)
+		result, err := handler.HTTPClient.BlockSearch(ctx, query, page, pageSize)
 		if err != nil {
 			return nil, errors.WithStack(err)
 		}
 
 		txs := []cosmossdk.HistoryTx{}
 		for _, b := range result.Blocks {
+			// stop fetching block results once the request is cancelled or times out
+			if err := ctx.Err(); err != nil {
+				return nil, errors.WithStack(err)
+			}
+
 			// fetch block results for each block found so we can inspect the block events
-			blockResult, err := handler.HTTPClient.BlockResults(int(b.Block.Height))
+			blockResult, err := handler.HTTPClient.BlockResults(ctx, int(b.Block.Height))
 			if err != nil {
 				return nil, errors.WithStack(err)
 			}
@@ -133,7 +172,7 @@ func GetTxHistory(handler *Handler, pubkey string, cursor string, pageSize int)
 			eventCache := make(map[string]interface{})
 
 			for i := range blockResult.GetBlockEvents() {
-				tx, err := GetTxFromBlockEvents(eventCache, b.Block.Header, blockResult.GetBlockEvents(), i, handler.BlockService.Latest.Height, handler.Denom, handler.NativeFee)
+				tx, err := GetTxFromBlockEvents(eventCache, b.Block.Header, blockResult.GetBlockEvents(), i, handler.BlockService.LatestHeight(), handler.Denom, handler.NativeFee)
 				if err != nil {
 					return nil, errors.Wrap(err, "failed to get tx from block events")
 				}
@@ -163,7 +202,7 @@ func GetTxHistory(handler *Handler, pubkey string, cursor string, pageSize int)
 	sources := TxHistorySources(handler.HTTPClient, pubkey, handler.FormatTx)
 	sources["swap"] = cosmossdk.NewTxState(true, fmt.Sprintf(`"outbound.to='%s'"`, pubkey), request)
 
-	res, err := handler.HTTPClient.GetTxHistory(pubkey, cursor, pageSize, sources)
+	res, err := handler.HTTPClient.GetTxHistory(ctx, pubkey, cursor, pageSize, sources)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to get tx history")
 	}
@@ -449,7 +488,7 @@ func GetTxFromBlockEvents(eventCache map[string]interface{}, blockHeader tenderm
 }
 
 // formatBlockTx creates a synthetic transaction from a BlockEndEvent
-func formatBlockTx(tx *BlockResultTx) (*cosmossdk.Tx, error) {
+func formatBlockTx(ctx context.Context, tx *BlockResultTx) (*cosmossdk.Tx, error) {
 	t := &cosmossdk.Tx{
 		BaseTx: api.BaseTx{
 			TxID:        tx.TxID,
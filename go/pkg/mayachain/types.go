@@ -1,6 +1,8 @@
 package mayachain
 
 import (
+	"context"
+
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/shapeshift/unchained/shared/cosmossdk"
 	coretypes "github.com/tendermint/tendermint/rpc/core/types"
@@ -58,7 +60,7 @@ type BlockResultTx struct {
 	Messages     []cosmossdk.Message
 	TypedEvent   TypedEvent
 	latestHeight int
-	formatTx     func(tx *BlockResultTx) (*cosmossdk.Tx, error)
+	formatTx     func(ctx context.Context, tx *BlockResultTx) (*cosmossdk.Tx, error)
 }
 
 func (r *BlockResultTx) GetHeight() int64 {
@@ -73,8 +75,8 @@ func (r *BlockResultTx) GetTxID() string {
 	return r.TxID
 }
 
-func (r *BlockResultTx) FormatTx() (*cosmossdk.Tx, error) {
-	return r.formatTx(r)
+func (r *BlockResultTx) FormatTx(ctx context.Context) (*cosmossdk.Tx, error) {
+	return r.formatTx(ctx, r)
 }
 
 type ResultBlockResults struct {
@@ -87,7 +89,7 @@ func (r *ResultBlockResults) GetBlockEvents() []cosmossdk.ABCIEvent {
 
 type ResultTx struct {
 	*coretypes.ResultTx
-	formatTx func(tx *coretypes.ResultTx) (*cosmossdk.Tx, error)
+	formatTx func(ctx context.Context, tx *coretypes.ResultTx) (*cosmossdk.Tx, error)
 }
 
 func (r *ResultTx) GetHeight() int64 {
@@ -102,8 +104,8 @@ func (r *ResultTx) GetTxID() string {
 	return r.Hash.String()
 }
 
-func (r *ResultTx) FormatTx() (*cosmossdk.Tx, error) {
-	return r.formatTx(r.ResultTx)
+func (r *ResultTx) FormatTx(ctx context.Context) (*cosmossdk.Tx, error) {
+	return r.formatTx(ctx, r.ResultTx)
 }
 
 type SigningTx interface {
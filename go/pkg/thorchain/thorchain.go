@@ -1,6 +1,7 @@
 package thorchain
 
 import (
+	"context"
 	"fmt"
 	"math/big"
 	"net/url"
@@ -24,6 +25,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/shapeshift/unchained/shared/cosmossdk"
 	"github.com/shapeshift/unchained/shared/log"
+	"github.com/shapeshift/unchained/shared/metrics"
 )
 
 var logger = log.WithoutFields()
@@ -38,11 +40,11 @@ type APIClient interface {
 	cosmossdk.APIClient
 
 	// Block
-	BlockSearch(query string, page int, pageSize int) (*coretypes.ResultBlockSearch, error)
+	BlockSearch(ctx context.Context, query string, page int, pageSize int) (*coretypes.ResultBlockSearch, error)
 
 	// Transactions
-	GetTx(txid string) (*coretypes.ResultTx, error)
-	TxSearch(query string, page int, pageSize int) (*coretypes.ResultTxSearch, error)
+	GetTx(ctx context.Context, txid string) (*coretypes.ResultTx, error)
+	TxSearch(ctx context.Context, query string, page int, pageSize int) (*coretypes.ResultTxSearch, error)
 
 	// Utility
 	GetEncoding() *params.EncodingConfig
@@ -53,8 +55,8 @@ type HTTPClient struct {
 	Indexer *resty.Client
 }
 
-func NewHTTPClient(conf Config) (*HTTPClient, error) {
-	httpClient, err := cosmossdk.NewHTTPClient(conf.Config)
+func NewHTTPClient(conf Config, prometheus *metrics.Prometheus) (*HTTPClient, error) {
+	httpClient, err := cosmossdk.NewHTTPClient(conf.Config, prometheus)
 	if err != nil {
 		logger.Panicf("failed to create new http client: %+v", err)
 	}
@@ -69,7 +71,12 @@ func NewHTTPClient(conf Config) (*HTTPClient, error) {
 	}
 
 	headers := map[string]string{"Accept": "application/json"}
-	indexer := resty.New().SetBaseURL(indexerURL.String()).SetHeaders(headers)
+	indexer, err := cosmossdk.NewUpstreamClient("indexer", []cosmossdk.Endpoint{{Name: indexerURL.Host, URL: indexerURL}}, prometheus)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create indexer client")
+	}
+
+	indexer.SetHeaders(headers)
 
 	c := &HTTPClient{
 		HTTPClient: httpClient,
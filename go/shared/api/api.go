@@ -2,11 +2,16 @@
 // along with useful middlewares for an http server.
 package api
 
+import "context"
+
 // Generic api error for handling failed requests
 // swagger:model ApiError
 type Error struct {
 	// required: true
 	Message string `json:"message"`
+	// example: TX_NOT_FOUND
+	Code    string        `json:"code,omitempty"`
+	Details *ErrorDetails `json:"details,omitempty"`
 }
 
 // Contains info about a 400 Bad Request response
@@ -14,6 +19,23 @@ type Error struct {
 type BadRequestError struct {
 	// required: true
 	Error string `json:"error"`
+	// example: INVALID_TX
+	Code    string        `json:"code,omitempty"`
+	Details *ErrorDetails `json:"details,omitempty"`
+}
+
+// Contains the details of a request failed by an upstream node
+// swagger:model ErrorDetails
+type ErrorDetails struct {
+	// example: sdk
+	Codespace string `json:"codespace,omitempty"`
+	// example: 5
+	Code uint32 `json:"code,omitempty"`
+	// example: insufficient funds
+	Log string `json:"log,omitempty"`
+	// http status code of the upstream response
+	// example: 503
+	Status int `json:"status,omitempty"`
 }
 
 // Contains info about a 422 Validation Error response
@@ -30,6 +52,8 @@ type ValidationError struct {
 // swagger:model InternalServerError
 type InternalServerError struct {
 	Message string `json:"message"`
+	// example: INTERNAL_ERROR
+	Code string `json:"code,omitempty"`
 }
 
 type Info interface {
@@ -158,6 +182,14 @@ type PubkeyParam struct {
 	Pubkey string `json:"pubkey"`
 }
 
+// swagger:parameters Stream
+type StreamParam struct {
+	PubkeyParam
+	// Event id of the last event received to resume the stream from
+	// in: header
+	LastEventID string `json:"Last-Event-ID"`
+}
+
 // swagger:parameters GetValidators
 type PaginationParam struct {
 	// Pagination cursor from previous response or empty string for first page fetch
@@ -190,8 +222,8 @@ type GasAmount string
 
 // BaseAPI interface for all coinstacks to implement
 type BaseAPI interface {
-	GetInfo() (Info, error)
-	GetAccount(pubkey string) (Account, error)
-	GetTxHistory(pubkey string, cursor string, pageSize int) (TxHistory, error)
-	SendTx(hex string) (string, error)
+	GetInfo(ctx context.Context) (Info, error)
+	GetAccount(ctx context.Context, pubkey string) (Account, error)
+	GetTxHistory(ctx context.Context, pubkey string, cursor string, pageSize int) (TxHistory, error)
+	SendTx(ctx context.Context, hex string) (string, error)
 }
@@ -0,0 +1,222 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/shapeshift/unchained/shared/config"
+	"github.com/shapeshift/unchained/shared/metrics"
+	"golang.org/x/time/rate"
+)
+
+// RouteClass groups routes sharing a rate limit
+type RouteClass string
+
+const (
+	RouteClassReads     RouteClass = "reads"
+	RouteClassBroadcast RouteClass = "broadcast"
+	RouteClassProxy     RouteClass = "proxy"
+)
+
+var routeClasses = []RouteClass{RouteClassReads, RouteClassBroadcast, RouteClassProxy}
+
+// label used for requests not made with an api key
+const anonymousKey = "anonymous"
+
+// APIKeysConfig is the format of the api keys config file (json or yaml)
+type APIKeysConfig struct {
+	// default rate limits by route class, unlimited if not set
+	Limits map[RouteClass]RateLimit `mapstructure:"limits"`
+	Keys   []APIKey                 `mapstructure:"keys"`
+}
+
+// APIKey grants access to all authenticated routes
+type APIKey struct {
+	// name of the key holder, used in logs and metric labels instead of the key itself
+	Name string `mapstructure:"name"`
+	Key  string `mapstructure:"key"`
+	// rate limits by route class, overriding the default rate limits
+	Limits map[RouteClass]RateLimit `mapstructure:"limits"`
+}
+
+// client is an authenticated api key with a rate limiter for each rate limited route class
+type client struct {
+	name     string
+	limiters map[RouteClass]*rate.Limiter
+}
+
+// Auth authenticates requests and websocket upgrades using api keys and enforces the rate limits of each key
+type Auth struct {
+	clients    map[string]*client
+	prometheus *metrics.Prometheus
+}
+
+// NewAuthFromEnv loads api keys from the config file at API_KEYS_PATH.
+// Authentication is disabled if API_KEYS_PATH is not set.
+func NewAuthFromEnv(prometheus *metrics.Prometheus) (*Auth, error) {
+	path := os.Getenv("API_KEYS_PATH")
+	if path == "" {
+		logger.Warn("API_KEYS_PATH not set: api key authentication disabled")
+		return NewAuth(nil, prometheus)
+	}
+
+	conf := &APIKeysConfig{}
+	if err := config.Load(path, conf); err != nil {
+		return nil, errors.Wrap(err, "failed to load api keys config")
+	}
+
+	return NewAuth(conf, prometheus)
+}
+
+// NewAuth creates an Auth for the api keys provided, or with authentication disabled if conf is nil
+func NewAuth(conf *APIKeysConfig, prometheus *metrics.Prometheus) (*Auth, error) {
+	a := &Auth{prometheus: prometheus}
+
+	if conf == nil {
+		return a, nil
+	}
+
+	if err := validateLimits(conf.Limits); err != nil {
+		return nil, errors.Wrap(err, "invalid default limits")
+	}
+
+	a.clients = make(map[string]*client, len(conf.Keys))
+
+	for i, k := range conf.Keys {
+		if k.Name == "" || k.Key == "" {
+			return nil, errors.Errorf("invalid api key at index %d: name and key are required", i)
+		}
+
+		if _, ok := a.clients[k.Key]; ok {
+			return nil, errors.Errorf("duplicate api key: %s", k.Name)
+		}
+
+		if err := validateLimits(k.Limits); err != nil {
+			return nil, errors.Wrapf(err, "invalid limits for api key: %s", k.Name)
+		}
+
+		c := &client{name: k.Name, limiters: make(map[RouteClass]*rate.Limiter)}
+
+		for _, class := range routeClasses {
+			limit, ok := k.Limits[class]
+			if !ok {
+				limit, ok = conf.Limits[class]
+			}
+
+			if ok {
+				c.limiters[class] = newLimiter(limit)
+			}
+		}
+
+		a.clients[k.Key] = c
+	}
+
+	return a, nil
+}
+
+func validateLimits(limits map[RouteClass]RateLimit) error {
+	for class, limit := range limits {
+		switch class {
+		case RouteClassReads, RouteClassBroadcast, RouteClassProxy:
+		default:
+			return errors.Errorf("unknown route class: %s", class)
+		}
+
+		if limit.Rate <= 0 || limit.Burst < 1 {
+			return errors.Errorf("invalid %s limit: rate must be greater than 0 and burst at least 1", class)
+		}
+	}
+
+	return nil
+}
+
+// Middleware authenticates any api, proxy or websocket upgrade request and applies the rate limit of the api key for the route class.
+// Requests are rejected with 401 if the api key is missing or unknown, and with 429 if the rate limit is exceeded.
+func (a *Auth) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		class, ok := routeClass(r)
+		if a.clients == nil || !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		c, ok := a.clients[requestAPIKey(r)]
+		if !ok {
+			HandleError(w, http.StatusUnauthorized, "missing or invalid api key")
+			return
+		}
+
+		setRequestKey(w, c.name)
+
+		if l, ok := c.limiters[class]; ok {
+			if allowed, retryAfter := take(l); !allowed {
+				a.prometheus.Metrics.HTTPRateLimited.With(metrics.Labels{"key": c.name, "class": string(class)}).Inc()
+
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+				HandleError(w, http.StatusTooManyRequests, "rate limit exceeded")
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireAPIKey rejects requests without a valid api key with 401, even if api key authentication is disabled.
+// Used for routes that must never be anonymous (ex. registering urls for the service to post to).
+func (a *Auth) RequireAPIKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := a.clients[requestAPIKey(r)]; !ok {
+			HandleError(w, http.StatusUnauthorized, "missing or invalid api key")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// routeClass of the request, or false if the route does not require authentication (ex. health, metrics and docs)
+func routeClass(r *http.Request) (RouteClass, bool) {
+	path := r.URL.Path
+
+	switch {
+	case r.Header.Get("Upgrade") == "websocket":
+		return RouteClassReads, true
+	case path == "/api/v1/send":
+		return RouteClassBroadcast, true
+	case strings.HasPrefix(path, "/api/"):
+		return RouteClassReads, true
+	case isProxyPath(path):
+		return RouteClassProxy, true
+	default:
+		return "", false
+	}
+}
+
+func isProxyPath(path string) bool {
+	for _, prefix := range []string{"/lcd", "/rpc", "/midgard"} {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// requestAPIKey returns the api key of the request from the X-API-Key or Authorization bearer header.
+// Browser websocket and event stream clients are unable to set headers, so the apiKey query parameter is also supported.
+func requestAPIKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+
+	return r.URL.Query().Get("apiKey")
+}
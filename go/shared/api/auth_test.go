@@ -0,0 +1,195 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/shapeshift/unchained/shared/metrics"
+)
+
+func newTestAuth(t *testing.T, conf *APIKeysConfig) *Auth {
+	t.Helper()
+
+	a, err := NewAuth(conf, metrics.NewPrometheus("test"))
+	if err != nil {
+		t.Fatalf("failed to create auth: %+v", err)
+	}
+
+	return a
+}
+
+func serve(handler http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+
+func TestAuthMiddleware(t *testing.T) {
+	a := newTestAuth(t, &APIKeysConfig{Keys: []APIKey{{Name: "test", Key: "secret"}}})
+	handler := a.Middleware(okHandler)
+
+	tests := []struct {
+		name     string
+		path     string
+		header   map[string]string
+		expected int
+	}{
+		{name: "missing key", path: "/api/v1/info", expected: http.StatusUnauthorized},
+		{name: "invalid key", path: "/api/v1/info", header: map[string]string{"X-API-Key": "invalid"}, expected: http.StatusUnauthorized},
+		{name: "api key header", path: "/api/v1/info", header: map[string]string{"X-API-Key": "secret"}, expected: http.StatusOK},
+		{name: "bearer token", path: "/api/v1/info", header: map[string]string{"Authorization": "Bearer secret"}, expected: http.StatusOK},
+		{name: "query parameter", path: "/api/v1/info?apiKey=secret", expected: http.StatusOK},
+		{name: "invalid query parameter", path: "/api/v1/info?apiKey=invalid", expected: http.StatusUnauthorized},
+		{name: "header preferred over query parameter", path: "/api/v1/info?apiKey=secret", header: map[string]string{"X-API-Key": "invalid"}, expected: http.StatusUnauthorized},
+		{name: "websocket upgrade", path: "/", header: map[string]string{"Upgrade": "websocket"}, expected: http.StatusUnauthorized},
+		{name: "proxy", path: "/lcd/cosmos/base/tendermint/v1beta1/blocks/latest", expected: http.StatusUnauthorized},
+		{name: "unauthenticated route", path: "/health", expected: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			for k, v := range tt.header {
+				r.Header.Set(k, v)
+			}
+
+			if w := serve(handler, r); w.Code != tt.expected {
+				t.Errorf("expected status %d, got %d", tt.expected, w.Code)
+			}
+		})
+	}
+}
+
+func TestAuthDisabled(t *testing.T) {
+	a := newTestAuth(t, nil)
+
+	if w := serve(a.Middleware(okHandler), httptest.NewRequest(http.MethodGet, "/api/v1/info", nil)); w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	// routes requiring an api key are rejected as no api key can be valid
+	if w := serve(a.RequireAPIKey(okHandler), httptest.NewRequest(http.MethodPost, "/api/v1/webhooks", nil)); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
+
+func TestRequireAPIKey(t *testing.T) {
+	a := newTestAuth(t, &APIKeysConfig{Keys: []APIKey{{Name: "test", Key: "secret"}}})
+	handler := a.RequireAPIKey(okHandler)
+
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/webhooks", nil)
+	if w := serve(handler, r); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+
+	r.Header.Set("X-API-Key", "secret")
+	if w := serve(handler, r); w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestAuthRateLimit(t *testing.T) {
+	a := newTestAuth(t, &APIKeysConfig{
+		Limits: map[RouteClass]RateLimit{RouteClassReads: {Rate: 1, Burst: 2}},
+		Keys: []APIKey{
+			{Name: "default", Key: "default"},
+			{Name: "override", Key: "override", Limits: map[RouteClass]RateLimit{RouteClassReads: {Rate: 1, Burst: 1}}},
+		},
+	})
+	handler := a.Middleware(okHandler)
+
+	request := func(key string, path string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.Header.Set("X-API-Key", key)
+		return serve(handler, r)
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := request("default", "/api/v1/info"); w.Code != http.StatusOK {
+			t.Fatalf("request %d: expected status %d, got %d", i, http.StatusOK, w.Code)
+		}
+	}
+
+	w := request("default", "/api/v1/info")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status %d, got %d", http.StatusTooManyRequests, w.Code)
+	}
+
+	if retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After")); err != nil || retryAfter < 1 {
+		t.Errorf("expected Retry-After of at least 1 second, got %q", w.Header().Get("Retry-After"))
+	}
+
+	// each route class has its own bucket, and classes without a limit are unlimited
+	if w := request("default", "/api/v1/send"); w.Code != http.StatusOK {
+		t.Errorf("expected broadcast to be allowed, got %d", w.Code)
+	}
+
+	// each api key has its own bucket, with limits overriding the default limits
+	if w := request("override", "/api/v1/info"); w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	if w := request("override", "/api/v1/info"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected status %d, got %d", http.StatusTooManyRequests, w.Code)
+	}
+}
+
+func TestRouteClass(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		upgrade  bool
+		expected RouteClass
+		ok       bool
+	}{
+		{name: "websocket", path: "/", upgrade: true, expected: RouteClassReads, ok: true},
+		{name: "send", path: "/api/v1/send", expected: RouteClassBroadcast, ok: true},
+		{name: "api", path: "/api/v1/account/addr1", expected: RouteClassReads, ok: true},
+		{name: "lcd", path: "/lcd/cosmos/bank/v1beta1/balances/addr1", expected: RouteClassProxy, ok: true},
+		{name: "rpc", path: "/rpc", expected: RouteClassProxy, ok: true},
+		{name: "midgard", path: "/midgard/v2/health", expected: RouteClassProxy, ok: true},
+		{name: "proxy prefix only", path: "/rpcx", ok: false},
+		{name: "health", path: "/health", ok: false},
+		{name: "metrics", path: "/metrics", ok: false},
+		{name: "docs", path: "/docs/", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.upgrade {
+				r.Header.Set("Upgrade", "websocket")
+			}
+
+			class, ok := routeClass(r)
+			if class != tt.expected || ok != tt.ok {
+				t.Errorf("expected %q %t, got %q %t", tt.expected, tt.ok, class, ok)
+			}
+		})
+	}
+}
+
+func TestNewAuthInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		conf *APIKeysConfig
+	}{
+		{name: "missing key", conf: &APIKeysConfig{Keys: []APIKey{{Name: "test"}}}},
+		{name: "duplicate key", conf: &APIKeysConfig{Keys: []APIKey{{Name: "a", Key: "secret"}, {Name: "b", Key: "secret"}}}},
+		{name: "unknown route class", conf: &APIKeysConfig{Limits: map[RouteClass]RateLimit{"writes": {Rate: 1, Burst: 1}}}},
+		{name: "invalid rate", conf: &APIKeysConfig{Limits: map[RouteClass]RateLimit{RouteClassReads: {Rate: 0, Burst: 1}}}},
+		{name: "invalid burst", conf: &APIKeysConfig{Keys: []APIKey{{Name: "test", Key: "secret", Limits: map[RouteClass]RateLimit{RouteClassReads: {Rate: 1}}}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewAuth(tt.conf, metrics.NewPrometheus("test")); err == nil {
+				t.Error("expected config to be rejected")
+			}
+		})
+	}
+}
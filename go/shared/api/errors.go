@@ -0,0 +1,119 @@
+package api
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/pkg/errors"
+)
+
+// Stable error codes returned to clients along with the http status of a failed request
+const (
+	ErrorCodeBadRequest      = "BAD_REQUEST"
+	ErrorCodeNotFound        = "NOT_FOUND"
+	ErrorCodeTxNotFound      = "TX_NOT_FOUND"
+	ErrorCodeInvalidTx       = "INVALID_TX"
+	ErrorCodeTxRejected      = "TX_REJECTED"
+	ErrorCodeUpstream        = "UPSTREAM_ERROR"
+	ErrorCodeUpstreamTimeout = "UPSTREAM_TIMEOUT"
+	ErrorCodeUnavailable     = "UNAVAILABLE"
+	ErrorCodeCanceled        = "REQUEST_CANCELED"
+	ErrorCodeInternal        = "INTERNAL_ERROR"
+)
+
+// StatusClientClosedRequest is the non standard status (nginx) of a request canceled by the client before a response was written
+const StatusClientClosedRequest = 499
+
+// APIError is an error with the http status and stable error code to respond with, along with any details of an upstream failure
+type APIError struct {
+	Status  int
+	Code    string
+	Message string
+	Details *ErrorDetails
+	cause   error
+}
+
+// NewAPIError creates an error with the http status and error code provided
+func NewAPIError(status int, code string, message string) *APIError {
+	return &APIError{Status: status, Code: code, Message: message}
+}
+
+// NewTxNotFoundError is returned if a tx does not exist on chain
+func NewTxNotFoundError(txid string) *APIError {
+	return NewAPIError(http.StatusNotFound, ErrorCodeTxNotFound, "tx not found: "+txid)
+}
+
+// NewInvalidTxError is returned if a tx is malformed and could not be decoded
+func NewInvalidTxError(message string, details *ErrorDetails) *APIError {
+	return NewAPIError(http.StatusBadRequest, ErrorCodeInvalidTx, message).WithDetails(details)
+}
+
+// NewTxRejectedError is returned if a valid tx is rejected by the node (ex. insufficient funds or invalid sequence)
+func NewTxRejectedError(message string, details *ErrorDetails) *APIError {
+	return NewAPIError(http.StatusUnprocessableEntity, ErrorCodeTxRejected, message).WithDetails(details)
+}
+
+// NewUpstreamError is returned if an upstream node failed to respond successfully, classifying any timeout as a 504 instead of a 502
+func NewUpstreamError(err error, message string) *APIError {
+	if isTimeout(err) {
+		return NewAPIError(http.StatusGatewayTimeout, ErrorCodeUpstreamTimeout, message).WithCause(err)
+	}
+
+	return NewAPIError(http.StatusBadGateway, ErrorCodeUpstream, message).WithCause(err)
+}
+
+// NewUnavailableError is returned if a request can not be served until the service catches up (ex. a full queue) and may be retried later
+func NewUnavailableError(message string) *APIError {
+	return NewAPIError(http.StatusServiceUnavailable, ErrorCodeUnavailable, message)
+}
+
+// WithDetails sets the details of the upstream failure
+func (e *APIError) WithDetails(details *ErrorDetails) *APIError {
+	e.Details = details
+	return e
+}
+
+// WithCause sets the underlying error, which is not exposed to clients
+func (e *APIError) WithCause(err error) *APIError {
+	e.cause = err
+	return e
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+func (e *APIError) Unwrap() error {
+	return e.cause
+}
+
+// AsAPIError returns the APIError within the error chain. Any other error is classified as a canceled request if the client
+// disconnected, as an upstream failure if caused by a failed request to an upstream node, otherwise as an internal server error.
+func AsAPIError(err error) *APIError {
+	apiErr := &APIError{}
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return NewAPIError(StatusClientClosedRequest, ErrorCodeCanceled, "request canceled").WithCause(err)
+	}
+
+	urlErr := &url.Error{}
+	if isTimeout(err) || errors.As(err, &urlErr) {
+		return NewUpstreamError(err, err.Error())
+	}
+
+	return NewAPIError(http.StatusInternalServerError, ErrorCodeInternal, err.Error()).WithCause(err)
+}
+
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
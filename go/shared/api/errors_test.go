@@ -0,0 +1,35 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestAsAPIError(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{name: "api error", err: errors.Wrap(NewTxNotFoundError("txid"), "failed to get tx"), status: http.StatusNotFound, code: ErrorCodeTxNotFound},
+		{name: "canceled", err: errors.Wrap(context.Canceled, "failed to get tx"), status: StatusClientClosedRequest, code: ErrorCodeCanceled},
+		{name: "canceled upstream request", err: &url.Error{Op: "Get", URL: "http://lcd", Err: context.Canceled}, status: StatusClientClosedRequest, code: ErrorCodeCanceled},
+		{name: "timeout", err: errors.Wrap(context.DeadlineExceeded, "failed to get tx"), status: http.StatusGatewayTimeout, code: ErrorCodeUpstreamTimeout},
+		{name: "upstream", err: &url.Error{Op: "Get", URL: "http://lcd", Err: errors.New("connection refused")}, status: http.StatusBadGateway, code: ErrorCodeUpstream},
+		{name: "internal", err: errors.New("failed to decode tx"), status: http.StatusInternalServerError, code: ErrorCodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := AsAPIError(tt.err)
+			if e.Status != tt.status || e.Code != tt.code {
+				t.Errorf("expected %d %s, got %d %s", tt.status, tt.code, e.Status, e.Code)
+			}
+		})
+	}
+}
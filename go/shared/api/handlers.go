@@ -18,6 +18,17 @@ func HandleResponse(w http.ResponseWriter, status int, res interface{}) {
 }
 
 func HandleError(w http.ResponseWriter, status int, message string) {
+	writeError(w, status, message, "", nil)
+}
+
+// HandleAPIError responds with the status, error code and any upstream details of the error.
+// Errors not created as an APIError are classified by cause (ex. upstream timeouts) or default to an internal server error.
+func HandleAPIError(w http.ResponseWriter, err error) {
+	e := AsAPIError(err)
+	writeError(w, e.Status, e.Error(), e.Code, e.Details)
+}
+
+func writeError(w http.ResponseWriter, status int, message string, code string, details *ErrorDetails) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 
@@ -25,11 +36,11 @@ func HandleError(w http.ResponseWriter, status int, message string) {
 
 	switch status {
 	case http.StatusBadRequest:
-		e = BadRequestError{Error: message}
+		e = BadRequestError{Error: message, Code: code, Details: details}
 	case http.StatusInternalServerError:
-		e = InternalServerError{Message: message}
+		e = InternalServerError{Message: message, Code: code}
 	default:
-		e = Error{Message: message}
+		e = Error{Message: message, Code: code, Details: details}
 	}
 
 	if err := json.NewEncoder(w).Encode(e); err != nil {
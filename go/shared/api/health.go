@@ -0,0 +1,134 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	HealthStatusUp   = "up"
+	HealthStatusDown = "down"
+
+	DEFAULT_HEALTH_CHECK_TIMEOUT = 5 * time.Second
+)
+
+// HealthCheckFunc returns an error if the dependency checked is unhealthy
+type HealthCheckFunc = func(ctx context.Context) error
+
+// HealthCheck is a named readiness check of a dependency (ex. lcd, rpc or websocket)
+type HealthCheck struct {
+	Name  string
+	Check HealthCheckFunc
+}
+
+// HealthCheckStatus is the result of a single readiness check
+type HealthCheckStatus struct {
+	Status string `json:"status"`
+	// time taken to run the check
+	Duration string `json:"duration"`
+	// reason the check failed
+	Error string `json:"error,omitempty"`
+}
+
+// HealthStatus is the response of the liveness and readiness endpoints
+type HealthStatus struct {
+	Status    string `json:"status"`
+	Coinstack string `json:"coinstack"`
+	// result of each readiness check by name
+	Checks map[string]HealthCheckStatus `json:"checks,omitempty"`
+}
+
+// Health serves the liveness and readiness endpoints of a coinstack
+type Health struct {
+	coinstack string
+	checks    []HealthCheck
+	timeout   time.Duration
+}
+
+// NewHealth creates a Health with the readiness checks provided.
+// Each check is run with the timeout set by HEALTH_CHECK_TIMEOUT.
+func NewHealth(coinstack string, checks ...HealthCheck) *Health {
+	return &Health{
+		coinstack: coinstack,
+		checks:    checks,
+		timeout:   healthCheckTimeoutFromEnv(),
+	}
+}
+
+func healthCheckTimeoutFromEnv() time.Duration {
+	value := os.Getenv("HEALTH_CHECK_TIMEOUT")
+	if value == "" {
+		return DEFAULT_HEALTH_CHECK_TIMEOUT
+	}
+
+	if timeout, err := time.ParseDuration(value); err == nil && timeout > 0 {
+		return timeout
+	}
+
+	logger.Warnf("invalid HEALTH_CHECK_TIMEOUT: %s (defaulting to %s)", value, DEFAULT_HEALTH_CHECK_TIMEOUT)
+
+	return DEFAULT_HEALTH_CHECK_TIMEOUT
+}
+
+// Live responds with 200 as long as the process is able to serve requests, regardless of the state of any dependencies
+func (h *Health) Live(w http.ResponseWriter, r *http.Request) {
+	HandleResponse(w, http.StatusOK, HealthStatus{Status: HealthStatusUp, Coinstack: h.coinstack})
+}
+
+// Ready runs all readiness checks concurrently, responding with 503 and the status of each check if any check fails
+func (h *Health) Ready(w http.ResponseWriter, r *http.Request) {
+	status := h.Check(r.Context())
+
+	if status.Status != HealthStatusUp {
+		HandleResponse(w, http.StatusServiceUnavailable, status)
+		return
+	}
+
+	HandleResponse(w, http.StatusOK, status)
+}
+
+// Check runs all readiness checks concurrently and returns the combined status
+func (h *Health) Check(ctx context.Context) HealthStatus {
+	status := HealthStatus{
+		Status:    HealthStatusUp,
+		Coinstack: h.coinstack,
+		Checks:    make(map[string]HealthCheckStatus, len(h.checks)),
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	var m sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, c := range h.checks {
+		wg.Add(1)
+		go func(c HealthCheck) {
+			defer wg.Done()
+
+			start := time.Now()
+			err := c.Check(ctx)
+
+			result := HealthCheckStatus{Status: HealthStatusUp, Duration: time.Since(start).String()}
+			if err != nil {
+				result.Status = HealthStatusDown
+				result.Error = err.Error()
+			}
+
+			m.Lock()
+			defer m.Unlock()
+
+			status.Checks[c.Name] = result
+			if err != nil {
+				status.Status = HealthStatusDown
+			}
+		}(c)
+	}
+
+	wg.Wait()
+
+	return status
+}
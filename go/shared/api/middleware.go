@@ -1,24 +1,33 @@
 package api
 
 import (
+	"fmt"
+	"net"
 	"net/http"
+	"os"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/shapeshift/unchained/shared/log"
 	"github.com/shapeshift/unchained/shared/metrics"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
+const requestIDHeader = "X-Request-Id"
+
 type statusWriter struct {
 	http.ResponseWriter
 	status int
+	// name of the api key the request was authenticated with
+	key string
 }
 
 func newStatusWriter(w http.ResponseWriter) *statusWriter {
-	return &statusWriter{w, http.StatusOK}
+	return &statusWriter{w, http.StatusOK, anonymousKey}
 }
 
 func (w *statusWriter) WriteHeader(status int) {
@@ -26,10 +35,32 @@ func (w *statusWriter) WriteHeader(status int) {
 	w.ResponseWriter.WriteHeader(status)
 }
 
-// Logger middleware for request details and metrics
+// Unwrap returns the underlying response writer so http.ResponseController can flush and set deadlines on streamed responses
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// setRequestKey records the api key name of an authenticated request for logging and metrics
+func setRequestKey(w http.ResponseWriter, key string) {
+	if sw, ok := w.(*statusWriter); ok {
+		sw.key = key
+	}
+}
+
+// Logger middleware for request details and metrics.
+// Each request is assigned a request id, or uses the X-Request-Id provided, which is returned in the response headers
+// and added to the request context for log correlation.
 func Logger(prometheus *metrics.Prometheus) mux.MiddlewareFunc {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID := r.Header.Get(requestIDHeader)
+			if requestID == "" {
+				requestID = uuid.NewString()
+			}
+
+			w.Header().Set(requestIDHeader, requestID)
+			r = r.WithContext(log.ContextWithRequestID(r.Context(), requestID))
+
 			sw := newStatusWriter(w)
 
 			t := time.Now().UTC()
@@ -44,24 +75,71 @@ func Logger(prometheus *metrics.Prometheus) mux.MiddlewareFunc {
 
 			duration := time.Since(t)
 
-			statusLogger := log.WithFields(log.Fields{"method": r.Method, "statusCode": sw.status, "responseTime": duration.String()})
+			// api keys provided as a query parameter must not be logged or used as a metric label
+			requestURI := redactAPIKey(r)
+
+			statusLogger := log.WithContext(r.Context()).WithFields(log.Fields{"method": r.Method, "statusCode": sw.status, "responseTime": duration.String(), "key": sw.key})
 
-			if strings.HasPrefix(r.RequestURI, "/api/v1/") && sw.status != 404 {
-				labels := metrics.Labels{"method": r.Method, "route": r.RequestURI, "statusCode": strconv.Itoa(sw.status)}
+			if strings.HasPrefix(requestURI, "/api/v1/") && sw.status != 404 {
+				// label with the route template instead of the request uri so addresses and txids do not create a time series each
+				labels := metrics.Labels{"method": r.Method, "route": routeTemplate(r), "statusCode": strconv.Itoa(sw.status), "key": sw.key}
 
 				prometheus.Metrics.HTTPRequestCounter.With(labels).Inc()
 				prometheus.Metrics.HTTPRequestDurationSeconds.With(labels).Observe(duration.Seconds())
 
 				if sw.status < http.StatusOK || sw.status >= http.StatusBadRequest {
-					statusLogger.Errorf("%s", r.RequestURI)
+					statusLogger.Errorf("%s", requestURI)
 				} else {
-					statusLogger.Infof("%s from %s", r.RequestURI, r.RemoteAddr)
+					statusLogger.Infof("%s from %s", requestURI, r.RemoteAddr)
 				}
 			}
 		})
 	}
 }
 
+// Tracing middleware creates a span for each request named by the route template, continuing any trace propagated by the client.
+// Websocket upgrades are not traced as the connection outlives the request.
+func Tracing(next http.Handler) http.Handler {
+	traced := otelhttp.NewHandler(next, "http.server", otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+		return fmt.Sprintf("%s %s", r.Method, routeTemplate(r))
+	}))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") == "websocket" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		traced.ServeHTTP(w, r)
+	})
+}
+
+// routeTemplate returns the path template of the matched route (ex. /api/v1/account/{pubkey}), or the request path if no route was matched
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+
+	return r.URL.Path
+}
+
+// redactAPIKey returns the request uri with the value of any apiKey query parameter removed
+func redactAPIKey(r *http.Request) string {
+	query := r.URL.Query()
+	if !query.Has("apiKey") {
+		return r.RequestURI
+	}
+
+	query.Set("apiKey", "redacted")
+
+	u := *r.URL
+	u.RawQuery = query.Encode()
+
+	return u.RequestURI()
+}
+
 func Scheme(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// De-facto standard header keys
@@ -109,3 +187,83 @@ func Scheme(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// trustedProxies are the proxies, loaded from TRUSTED_PROXIES as a comma separated list of ips or cidrs, whose X-Forwarded-For hops are trusted
+var trustedProxies = trustedProxiesFromEnv()
+
+func trustedProxiesFromEnv() []*net.IPNet {
+	proxies := []*net.IPNet{}
+
+	value := os.Getenv("TRUSTED_PROXIES")
+	if value == "" {
+		return proxies
+	}
+
+	for _, proxy := range strings.Split(value, ",") {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+
+		if !strings.Contains(proxy, "/") {
+			if ip := net.ParseIP(proxy); ip != nil && ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			logger.Warnf("invalid TRUSTED_PROXIES entry: %s (ignoring)", proxy)
+			continue
+		}
+
+		proxies = append(proxies, ipNet)
+	}
+
+	return proxies
+}
+
+func isTrustedProxy(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+
+	for _, proxy := range trustedProxies {
+		if proxy.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ClientIP returns the ip of the client connected to the server.
+// If connected through a trusted proxy, the rightmost X-Forwarded-For address not belonging to a trusted proxy is returned instead,
+// as any addresses before the hops added by trusted proxies are set by the client and cannot be trusted.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	if !isTrustedProxy(host) {
+		return host
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+
+		if !isTrustedProxy(hop) {
+			return hop
+		}
+	}
+
+	return host
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 192.168.1.1")
+	trustedProxies = trustedProxiesFromEnv()
+	t.Cleanup(func() { trustedProxies = nil })
+
+	tests := []struct {
+		name          string
+		remoteAddr    string
+		xForwardedFor string
+		expected      string
+	}{
+		{"direct client", "203.0.113.1:1234", "", "203.0.113.1"},
+		{"direct client spoofing forwarded header", "203.0.113.1:1234", "198.51.100.1", "203.0.113.1"},
+		{"trusted proxy", "10.0.0.1:1234", "203.0.113.1", "203.0.113.1"},
+		{"trusted proxy with client spoofed hop", "10.0.0.1:1234", "198.51.100.1, 203.0.113.1", "203.0.113.1"},
+		{"chained trusted proxies", "10.0.0.1:1234", "198.51.100.1, 203.0.113.1, 192.168.1.1", "203.0.113.1"},
+		{"trusted proxy without forwarded header", "10.0.0.1:1234", "", "10.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.xForwardedFor != "" {
+				r.Header.Set("X-Forwarded-For", tt.xForwardedFor)
+			}
+
+			if ip := ClientIP(r); ip != tt.expected {
+				t.Fatalf("expected: %s, got: %s", tt.expected, ip)
+			}
+		})
+	}
+}
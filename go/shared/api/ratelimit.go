@@ -0,0 +1,30 @@
+package api
+
+import (
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// RateLimit configures a token bucket refilled at Rate requests per second up to a max of Burst requests
+type RateLimit struct {
+	Rate  float64 `mapstructure:"rate"`
+	Burst int     `mapstructure:"burst"`
+}
+
+func newLimiter(limit RateLimit) *rate.Limiter {
+	return rate.NewLimiter(rate.Limit(limit.Rate), limit.Burst)
+}
+
+// take a token from the bucket if available, otherwise return the time until the next token is available
+func take(l *rate.Limiter) (bool, time.Duration) {
+	r := l.Reserve()
+
+	if delay := r.Delay(); delay > 0 {
+		// return the token so a rejected request does not delay the next request
+		r.Cancel()
+		return false, delay
+	}
+
+	return true, 0
+}
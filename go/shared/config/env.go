@@ -0,0 +1,59 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/shapeshift/unchained/shared/log"
+)
+
+var logger = log.WithoutFields()
+
+// IntFromEnv returns the value of the environment variable as an int, or the default value if unset or invalid (less than the min value)
+func IntFromEnv(key string, defaultValue int, minValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	if v, err := strconv.Atoi(value); err == nil && v >= minValue {
+		return v
+	}
+
+	logger.Warnf("invalid %s: %s (defaulting to %d)", key, value, defaultValue)
+
+	return defaultValue
+}
+
+// DurationFromEnv returns the value of the environment variable as a duration, or the default value if unset or invalid (not positive)
+func DurationFromEnv(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	if v, err := time.ParseDuration(value); err == nil && v > 0 {
+		return v
+	}
+
+	logger.Warnf("invalid %s: %s (defaulting to %s)", key, value, defaultValue)
+
+	return defaultValue
+}
+
+// BoolFromEnv returns the value of the environment variable as a bool, or the default value if unset or invalid
+func BoolFromEnv(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	if v, err := strconv.ParseBool(value); err == nil {
+		return v
+	}
+
+	logger.Warnf("invalid %s: %s (defaulting to %t)", key, value, defaultValue)
+
+	return defaultValue
+}
@@ -1,6 +1,7 @@
 package cosmossdk
 
 import (
+	"context"
 	"fmt"
 	"math/big"
 	"strconv"
@@ -9,7 +10,7 @@ import (
 	"github.com/pkg/errors"
 )
 
-func (c *HTTPClient) GetAccount(address string) (*AccountResponse, error) {
+func (c *HTTPClient) GetAccount(ctx context.Context, address string) (*AccountResponse, error) {
 	var res struct {
 		Account struct {
 			Type    string `json:"@type"`
@@ -23,7 +24,7 @@ func (c *HTTPClient) GetAccount(address string) (*AccountResponse, error) {
 		} `json:"account"`
 	}
 
-	_, err := c.LCD.R().SetResult(&res).Get(fmt.Sprintf("/cosmos/auth/v1beta1/accounts/%s", address))
+	_, err := c.LCD.R().SetContext(ctx).SetResult(&res).Get(fmt.Sprintf("/cosmos/auth/v1beta1/accounts/%s", address))
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get account")
 	}
@@ -42,13 +43,13 @@ func (c *HTTPClient) GetAccount(address string) (*AccountResponse, error) {
 	return a, nil
 }
 
-func (c *HTTPClient) GetBalance(address string, baseDenom string) (*BalanceResponse, error) {
+func (c *HTTPClient) GetBalance(ctx context.Context, address string, baseDenom string) (*BalanceResponse, error) {
 	var res struct {
 		Balances   []Value    `json:"balances"`
 		Pagination Pagination `json:"pagination"`
 	}
 
-	_, err := c.LCD.R().SetResult(&res).Get(fmt.Sprintf("/cosmos/bank/v1beta1/balances/%s", address))
+	_, err := c.LCD.R().SetContext(ctx).SetResult(&res).Get(fmt.Sprintf("/cosmos/bank/v1beta1/balances/%s", address))
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get balances")
 	}
@@ -56,7 +57,7 @@ func (c *HTTPClient) GetBalance(address string, baseDenom string) (*BalanceRespo
 	return balance(res.Balances, baseDenom)
 }
 
-func (c *HTTPClient) GetDelegations(address string, apr *big.Float) ([]Delegation, error) {
+func (c *HTTPClient) GetDelegations(ctx context.Context, address string, apr *big.Float) ([]Delegation, error) {
 	var res struct {
 		DelegationResponses []struct {
 			Delegation struct {
@@ -69,14 +70,14 @@ func (c *HTTPClient) GetDelegations(address string, apr *big.Float) ([]Delegatio
 		Pagination Pagination `json:"pagination"`
 	}
 
-	_, err := c.LCD.R().SetResult(&res).Get(fmt.Sprintf("/cosmos/staking/v1beta1/delegations/%s", address))
+	_, err := c.LCD.R().SetContext(ctx).SetResult(&res).Get(fmt.Sprintf("/cosmos/staking/v1beta1/delegations/%s", address))
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get delegations")
 	}
 
 	delgations := []Delegation{}
 	for _, r := range res.DelegationResponses {
-		validator, err := c.GetValidator(r.Delegation.ValidatorAddress, apr)
+		validator, err := c.GetValidator(ctx, r.Delegation.ValidatorAddress, apr)
 		if err != nil {
 			validator = &Validator{Address: r.Delegation.ValidatorAddress}
 		}
@@ -95,7 +96,7 @@ func (c *HTTPClient) GetDelegations(address string, apr *big.Float) ([]Delegatio
 	return delgations, nil
 }
 
-func (c *HTTPClient) GetRedelegations(address string, apr *big.Float) ([]Redelegation, error) {
+func (c *HTTPClient) GetRedelegations(ctx context.Context, address string, apr *big.Float) ([]Redelegation, error) {
 	type Entry struct {
 		CreationHeight int       `json:"creation_height"`
 		CompletionTime time.Time `json:"completion_time"`
@@ -119,19 +120,19 @@ func (c *HTTPClient) GetRedelegations(address string, apr *big.Float) ([]Redeleg
 		Pagination Pagination `json:"pagination"`
 	}
 
-	_, err := c.LCD.R().SetResult(&res).Get(fmt.Sprintf("/cosmos/staking/v1beta1/delegators/%s/redelegations", address))
+	_, err := c.LCD.R().SetContext(ctx).SetResult(&res).Get(fmt.Sprintf("/cosmos/staking/v1beta1/delegators/%s/redelegations", address))
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get redelegations")
 	}
 
 	redelgations := []Redelegation{}
 	for _, r := range res.RedelegationResponses {
-		sourceValidator, err := c.GetValidator(r.Redelegation.ValidatorSrcAddress, apr)
+		sourceValidator, err := c.GetValidator(ctx, r.Redelegation.ValidatorSrcAddress, apr)
 		if err != nil {
 			sourceValidator = &Validator{Address: r.Redelegation.ValidatorSrcAddress}
 		}
 
-		destinationValidator, err := c.GetValidator(r.Redelegation.ValidatorDstAddress, apr)
+		destinationValidator, err := c.GetValidator(ctx, r.Redelegation.ValidatorDstAddress, apr)
 		if err != nil {
 			destinationValidator = &Validator{Address: r.Redelegation.ValidatorDstAddress}
 		}
@@ -158,7 +159,7 @@ func (c *HTTPClient) GetRedelegations(address string, apr *big.Float) ([]Redeleg
 	return redelgations, nil
 }
 
-func (c *HTTPClient) GetUnbondings(address string, baseDenom string, apr *big.Float) ([]Unbonding, error) {
+func (c *HTTPClient) GetUnbondings(ctx context.Context, address string, baseDenom string, apr *big.Float) ([]Unbonding, error) {
 	var res struct {
 		UnbondingResponses []struct {
 			DelegatorAddress string `json:"delegator_address"`
@@ -173,14 +174,14 @@ func (c *HTTPClient) GetUnbondings(address string, baseDenom string, apr *big.Fl
 		Pagination Pagination `json:"pagination"`
 	}
 
-	_, err := c.LCD.R().SetResult(&res).Get(fmt.Sprintf("/cosmos/staking/v1beta1/delegators/%s/unbonding_delegations", address))
+	_, err := c.LCD.R().SetContext(ctx).SetResult(&res).Get(fmt.Sprintf("/cosmos/staking/v1beta1/delegators/%s/unbonding_delegations", address))
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get unbondings")
 	}
 
 	unbondings := []Unbonding{}
 	for _, r := range res.UnbondingResponses {
-		validator, err := c.GetValidator(r.ValidatorAddress, apr)
+		validator, err := c.GetValidator(ctx, r.ValidatorAddress, apr)
 		if err != nil {
 			validator = &Validator{Address: r.ValidatorAddress}
 		}
@@ -204,7 +205,7 @@ func (c *HTTPClient) GetUnbondings(address string, baseDenom string, apr *big.Fl
 	return unbondings, nil
 }
 
-func (c *HTTPClient) GetRewards(address string, apr *big.Float) ([]Reward, error) {
+func (c *HTTPClient) GetRewards(ctx context.Context, address string, apr *big.Float) ([]Reward, error) {
 	var res struct {
 		Rewards []struct {
 			ValidatorAddress string `json:"validator_address"`
@@ -219,14 +220,14 @@ func (c *HTTPClient) GetRewards(address string, apr *big.Float) ([]Reward, error
 		} `json:"total"`
 	}
 
-	_, err := c.LCD.R().SetResult(&res).Get(fmt.Sprintf("/cosmos/distribution/v1beta1/delegators/%s/rewards", address))
+	_, err := c.LCD.R().SetContext(ctx).SetResult(&res).Get(fmt.Sprintf("/cosmos/distribution/v1beta1/delegators/%s/rewards", address))
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get unbondings")
 	}
 
 	rewards := []Reward{}
 	for _, r := range res.Rewards {
-		validator, err := c.GetValidator(r.ValidatorAddress, apr)
+		validator, err := c.GetValidator(ctx, r.ValidatorAddress, apr)
 		if err != nil {
 			validator = &Validator{Address: r.ValidatorAddress}
 		}
@@ -12,6 +12,7 @@ import (
 	ws "github.com/gorilla/websocket"
 	"github.com/pkg/errors"
 	"github.com/shapeshift/unchained/shared/api"
+	"github.com/shapeshift/unchained/shared/webhook"
 	"github.com/shapeshift/unchained/shared/websocket"
 )
 
@@ -26,6 +27,10 @@ var (
 	upgrader = ws.Upgrader{
 		ReadBufferSize:  1024,
 		WriteBufferSize: 1024,
+		// negotiate permessage-deflate compression with clients that support it
+		EnableCompression: true,
+		// negotiate the message encoding with clients that request one
+		Subprotocols: websocket.Subprotocols,
 		CheckOrigin: func(r *http.Request) bool {
 			return true
 		},
@@ -33,9 +38,10 @@ var (
 )
 
 type API struct {
-	handler RouteHandler
-	manager *websocket.Manager
-	server  *http.Server
+	handler  RouteHandler
+	manager  *websocket.Manager
+	server   *http.Server
+	webhooks *webhook.Service
 }
 
 func New(handler RouteHandler, manager *websocket.Manager, server *http.Server) *API {
@@ -55,6 +61,10 @@ func (a *API) Serve(errChan chan<- error) {
 		errChan <- errors.Wrap(err, "error starting websocket")
 	}
 
+	if a.webhooks != nil {
+		a.webhooks.Start()
+	}
+
 	go a.manager.Start()
 
 	if err := a.server.ListenAndServe(); err != nil {
@@ -63,14 +73,29 @@ func (a *API) Serve(errChan chan<- error) {
 }
 
 func (a *API) Shutdown() {
-	ctx, cancel := context.WithTimeout(context.Background(), GRACEFUL_SHUTDOWN)
-	defer cancel()
+	// ask clients to reconnect elsewhere before the upstream websocket and server are stopped
+	a.manager.Drain()
 
 	a.handler.StopWebsocket()
 
+	if a.webhooks != nil {
+		a.webhooks.Stop()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), GRACEFUL_SHUTDOWN)
+	defer cancel()
+
 	if err := a.server.Shutdown(ctx); err != nil {
 		logger.Errorf("error shutting down server: %+v", err)
 	}
+
+	// stopped last as in flight requests still rely on the upstream health
+	a.handler.StopUpstreams()
+}
+
+// WebhookService sets the service used to register and deliver webhooks
+func (a *API) WebhookService(service *webhook.Service) {
+	a.webhooks = service
 }
 
 func (a *API) Root(w http.ResponseWriter, r *http.Request) {
@@ -127,10 +152,22 @@ func (a *API) ValidatePagingParams(w http.ResponseWriter, r *http.Request, defau
 	return cursor, pageSize, nil
 }
 
+// Stream transactions for an account as server-sent events
+func (a *API) Stream(w http.ResponseWriter, r *http.Request) {
+	// pubkey validated by ValidatePubkey middleware
+	pubkey := mux.Vars(r)["pubkey"]
+
+	a.handler.NewStreamConnection(w, r, pubkey, a.manager)
+}
+
 func (a *API) Websocket(w http.ResponseWriter, r *http.Request) {
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		api.HandleError(w, http.StatusInternalServerError, err.Error())
+		api.HandleAPIError(w, err)
+		return
+	}
+
+	if !a.manager.Accept(conn, api.ClientIP(r)) {
 		return
 	}
 
@@ -138,9 +175,9 @@ func (a *API) Websocket(w http.ResponseWriter, r *http.Request) {
 }
 
 func (a *API) Info(w http.ResponseWriter, r *http.Request) {
-	info, err := a.handler.GetInfo()
+	info, err := a.handler.GetInfo(r.Context())
 	if err != nil {
-		api.HandleError(w, http.StatusInternalServerError, err.Error())
+		api.HandleAPIError(w, err)
 		return
 	}
 
@@ -151,9 +188,9 @@ func (a *API) Account(w http.ResponseWriter, r *http.Request) {
 	// pubkey validated by ValidatePubkey middleware
 	pubkey := mux.Vars(r)["pubkey"]
 
-	account, err := a.handler.GetAccount(pubkey)
+	account, err := a.handler.GetAccount(r.Context(), pubkey)
 	if err != nil {
-		api.HandleError(w, http.StatusInternalServerError, err.Error())
+		api.HandleAPIError(w, err)
 		return
 	}
 
@@ -170,9 +207,9 @@ func (a *API) TxHistory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	txHistory, err := a.handler.GetTxHistory(pubkey, cursor, pageSize)
+	txHistory, err := a.handler.GetTxHistory(r.Context(), pubkey, cursor, pageSize)
 	if err != nil {
-		api.HandleError(w, http.StatusInternalServerError, err.Error())
+		api.HandleAPIError(w, err)
 		return
 	}
 
@@ -186,9 +223,9 @@ func (a *API) Tx(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tx, err := a.handler.GetTx(txid)
+	tx, err := a.handler.GetTx(r.Context(), txid)
 	if err != nil {
-		api.HandleError(w, http.StatusInternalServerError, err.Error())
+		api.HandleAPIError(w, err)
 		return
 	}
 
@@ -204,9 +241,9 @@ func (a *API) SendTx(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	txHash, err := a.handler.SendTx(body.RawTx)
+	txHash, err := a.handler.SendTx(r.Context(), body.RawTx)
 	if err != nil {
-		api.HandleError(w, http.StatusInternalServerError, err.Error())
+		api.HandleAPIError(w, err)
 		return
 	}
 
@@ -222,11 +259,78 @@ func (a *API) EstimateGas(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	estimatedGas, err := a.handler.EstimateGas(body.RawTx)
+	estimatedGas, err := a.handler.EstimateGas(r.Context(), body.RawTx)
 	if err != nil {
-		api.HandleError(w, http.StatusInternalServerError, err.Error())
+		api.HandleAPIError(w, err)
 		return
 	}
 
 	api.HandleResponse(w, http.StatusOK, estimatedGas)
 }
+
+func (a *API) RegisterWebhook(w http.ResponseWriter, r *http.Request) {
+	body := &webhook.RegisterBody{}
+
+	err := json.NewDecoder(r.Body).Decode(body)
+	if err != nil {
+		api.HandleError(w, http.StatusBadRequest, "invalid post body")
+		return
+	}
+
+	wh, err := a.webhooks.Register(body.URL, body.Addresses)
+	if err != nil {
+		handleWebhookError(w, err)
+		return
+	}
+
+	api.HandleResponse(w, http.StatusCreated, wh)
+}
+
+func (a *API) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	wh, err := a.webhooks.Get(mux.Vars(r)["id"])
+	if err != nil {
+		handleWebhookError(w, err)
+		return
+	}
+
+	api.HandleResponse(w, http.StatusOK, wh)
+}
+
+func (a *API) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	if err := a.webhooks.Delete(mux.Vars(r)["id"]); err != nil {
+		handleWebhookError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *API) WebhookDeadLetters(w http.ResponseWriter, r *http.Request) {
+	deliveries, err := a.webhooks.DeadLetters(mux.Vars(r)["id"])
+	if err != nil {
+		handleWebhookError(w, err)
+		return
+	}
+
+	api.HandleResponse(w, http.StatusOK, deliveries)
+}
+
+func (a *API) RedeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	if err := a.webhooks.Redeliver(mux.Vars(r)["id"], mux.Vars(r)["deliveryId"]); err != nil {
+		handleWebhookError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func handleWebhookError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, webhook.ErrInvalidWebhook):
+		api.HandleError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, webhook.ErrNotFound):
+		api.HandleError(w, http.StatusNotFound, err.Error())
+	default:
+		api.HandleAPIError(w, err)
+	}
+}
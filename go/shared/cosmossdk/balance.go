@@ -0,0 +1,29 @@
+package cosmossdk
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/shapeshift/unchained/shared/websocket"
+)
+
+type BalanceFunc = func(ctx context.Context, address string, baseDenom string) (*BalanceResponse, error)
+
+// Balance contains the updated balance of an address pushed to websocket balance subscribers
+type Balance struct {
+	Pubkey  string  `json:"pubkey"`
+	Balance string  `json:"balance"`
+	Assets  []Value `json:"assets"`
+}
+
+// NewBalanceHandler creates a websocket balance handler that fetches the current balance and assets of an address
+func NewBalanceHandler(getBalance BalanceFunc, denom string) websocket.BalanceHandlerFunc {
+	return func(ctx context.Context, addr string) (interface{}, error) {
+		b, err := getBalance(ctx, addr, denom)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get balance for address: %s", addr)
+		}
+
+		return Balance{Pubkey: addr, Balance: b.Amount, Assets: b.Assets}, nil
+	}
+}
@@ -1,10 +1,12 @@
 package cosmossdk
 
 import (
+	"context"
+
 	"github.com/pkg/errors"
 )
 
-func (c *HTTPClient) GetTotalSupply(denom string) (string, error) {
+func (c *HTTPClient) GetTotalSupply(ctx context.Context, denom string) (string, error) {
 	var res struct {
 		Amount struct {
 			Amount string `json:"amount"`
@@ -16,7 +18,7 @@ func (c *HTTPClient) GetTotalSupply(denom string) (string, error) {
 		"denom": denom,
 	}
 
-	_, err := c.LCD.R().SetResult(&res).SetQueryParams(queryParams).Get("/cosmos/bank/v1beta1/supply/by_denom")
+	_, err := c.LCD.R().SetContext(ctx).SetResult(&res).SetQueryParams(queryParams).Get("/cosmos/bank/v1beta1/supply/by_denom")
 	if err != nil {
 		return "0", errors.Wrapf(err, "failed to get total supply of: %s", denom)
 	}
@@ -24,12 +26,12 @@ func (c *HTTPClient) GetTotalSupply(denom string) (string, error) {
 	return res.Amount.Amount, nil
 }
 
-func (c *HTTPClient) GetAnnualProvisions() (string, error) {
+func (c *HTTPClient) GetAnnualProvisions(ctx context.Context) (string, error) {
 	var res struct {
 		AnnualProvisions string `json:"annual_provisions"`
 	}
 
-	_, err := c.LCD.R().SetResult(&res).Get("/cosmos/mint/v1beta1/annual_provisions")
+	_, err := c.LCD.R().SetContext(ctx).SetResult(&res).Get("/cosmos/mint/v1beta1/annual_provisions")
 	if err != nil {
 		return "0", errors.Wrap(err, "failed to get annual provisions")
 	}
@@ -37,14 +39,14 @@ func (c *HTTPClient) GetAnnualProvisions() (string, error) {
 	return res.AnnualProvisions, nil
 }
 
-func (c *HTTPClient) GetCommunityTax() (string, error) {
+func (c *HTTPClient) GetCommunityTax(ctx context.Context) (string, error) {
 	var res struct {
 		Params struct {
 			CommunityTax string `json:"community_tax"`
 		} `json:"params"`
 	}
 
-	_, err := c.LCD.R().SetResult(&res).Get("/cosmos/distribution/v1beta1/params")
+	_, err := c.LCD.R().SetContext(ctx).SetResult(&res).Get("/cosmos/distribution/v1beta1/params")
 	if err != nil {
 		return "0", errors.Wrap(err, "failed to get community tax")
 	}
@@ -52,14 +54,14 @@ func (c *HTTPClient) GetCommunityTax() (string, error) {
 	return res.Params.CommunityTax, nil
 }
 
-func (c *HTTPClient) GetBondedTokens() (string, error) {
+func (c *HTTPClient) GetBondedTokens(ctx context.Context) (string, error) {
 	var res struct {
 		Pool struct {
 			BondedTokens string `json:"bonded_tokens"`
 		} `json:"pool"`
 	}
 
-	_, err := c.LCD.R().SetResult(&res).Get("/cosmos/staking/v1beta1/pool")
+	_, err := c.LCD.R().SetContext(ctx).SetResult(&res).Get("/cosmos/staking/v1beta1/pool")
 	if err != nil {
 		return "0", errors.Wrap(err, "failed to get bonded tokens")
 	}
@@ -1,10 +1,13 @@
 package cosmossdk
 
 import (
+	"context"
+	"encoding/json"
 	"sync"
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/shapeshift/unchained/shared/websocket"
 )
 
 type ResultBlock struct {
@@ -14,7 +17,7 @@ type ResultBlock struct {
 }
 
 type BlockFetcher interface {
-	GetBlock(height *int) (*ResultBlock, error)
+	GetBlock(ctx context.Context, height *int) (*ResultBlock, error)
 }
 
 type BlockService struct {
@@ -30,7 +33,7 @@ func NewBlockService(httpClient BlockFetcher) (*BlockService, error) {
 		httpClient: httpClient,
 	}
 
-	result, err := s.httpClient.GetBlock(nil)
+	result, err := s.httpClient.GetBlock(context.Background(), nil)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
@@ -55,6 +58,18 @@ func (s *BlockService) WriteBlock(block *BlockResponse, latest bool) {
 	s.m.Unlock()
 }
 
+// LatestHeight returns the height of the latest block, safe to call while new blocks are being written
+func (s *BlockService) LatestHeight() int {
+	s.m.RLock()
+	defer s.m.RUnlock()
+
+	if s.Latest == nil {
+		return 0
+	}
+
+	return s.Latest.Height
+}
+
 func (s *BlockService) ReadBlock(height int) (*BlockResponse, bool) {
 	s.m.RLock()
 	block, ok := s.Blocks[height]
@@ -62,7 +77,7 @@ func (s *BlockService) ReadBlock(height int) (*BlockResponse, bool) {
 	return block, ok
 }
 
-func (s *BlockService) GetBlock(height int) (*BlockResponse, error) {
+func (s *BlockService) GetBlock(ctx context.Context, height int) (*BlockResponse, error) {
 	s.m.RLock()
 	block, ok := s.Blocks[height]
 	s.m.RUnlock()
@@ -70,7 +85,7 @@ func (s *BlockService) GetBlock(height int) (*BlockResponse, error) {
 		return block, nil
 	}
 
-	result, err := s.httpClient.GetBlock(&height)
+	result, err := s.httpClient.GetBlock(ctx, &height)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
@@ -85,3 +100,32 @@ func (s *BlockService) GetBlock(height int) (*BlockResponse, error) {
 
 	return block, nil
 }
+
+// BlockSubscriber keeps the latest block up to date from messages published to the blocks topic.
+// Used by api replicas that receive blocks from the broker instead of the upstream node.
+type BlockSubscriber struct {
+	blockService *BlockService
+}
+
+func NewBlockSubscriber(blockService *BlockService) *BlockSubscriber {
+	return &BlockSubscriber{blockService: blockService}
+}
+
+func (s *BlockSubscriber) Send(msg *websocket.Message) {
+	data, err := msg.JSON()
+	if err != nil {
+		logger.Errorf("failed to encode block message: %+v", err)
+		return
+	}
+
+	res := struct {
+		Data Block `json:"data"`
+	}{}
+
+	if err := json.Unmarshal(data, &res); err != nil {
+		logger.Errorf("failed to unmarshal block message: %v", err)
+		return
+	}
+
+	s.blockService.WriteBlock(&BlockResponse{Height: res.Data.Height, Hash: res.Data.Hash, Timestamp: res.Data.Timestamp}, true)
+}
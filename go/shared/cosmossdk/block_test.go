@@ -0,0 +1,35 @@
+package cosmossdk
+
+import (
+	"sync"
+	"testing"
+)
+
+// TestLatestHeightConcurrent reads the latest height while new blocks are written and is intended to be run with the race detector enabled
+func TestLatestHeightConcurrent(t *testing.T) {
+	s := &BlockService{Blocks: make(map[int]*BlockResponse)}
+
+	if height := s.LatestHeight(); height != 0 {
+		t.Fatalf("expected height 0 without a latest block, got %d", height)
+	}
+
+	wg := sync.WaitGroup{}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for height := 1; height <= 100; height++ {
+			s.WriteBlock(&BlockResponse{Height: height}, true)
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		_ = s.LatestHeight()
+	}
+
+	wg.Wait()
+
+	if height := s.LatestHeight(); height != 100 {
+		t.Fatalf("expected height 100, got %d", height)
+	}
+}
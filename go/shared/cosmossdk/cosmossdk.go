@@ -2,15 +2,14 @@ package cosmossdk
 
 import (
 	"context"
-	"fmt"
 	"math/big"
 	"net/url"
-	"path"
 	"strings"
 
 	"github.com/go-resty/resty/v2"
 	"github.com/pkg/errors"
 	"github.com/shapeshift/unchained/shared/log"
+	"github.com/shapeshift/unchained/shared/metrics"
 )
 
 var logger = log.WithoutFields()
@@ -31,8 +30,62 @@ type Config struct {
 	WSAPIKEY          string
 }
 
+// Endpoint contains the connection details of a single upstream node
+type Endpoint struct {
+	// host of the endpoint, safe to use as a log field or metric label
+	Name   string
+	URL    *url.URL
+	APIKEY string
+}
+
+// WSFeed contains the connection details of a single upstream websocket feed
+type WSFeed = Endpoint
+
+// LCDEndpoints parses the comma separated LCDURL into one or more upstream lcd endpoints in order of preference.
+// LCDAPIKEY is either a single api key used for all endpoints or a comma separated list of api keys matching each url by position.
+func (c Config) LCDEndpoints() ([]Endpoint, error) {
+	return parseEndpoints("LCD", c.LCDURL, c.LCDAPIKEY)
+}
+
+// RPCEndpoints parses the comma separated RPCURL into one or more upstream rpc endpoints in order of preference.
+// RPCAPIKEY is either a single api key used for all endpoints or a comma separated list of api keys matching each url by position.
+func (c Config) RPCEndpoints() ([]Endpoint, error) {
+	return parseEndpoints("RPC", c.RPCURL, c.RPCAPIKEY)
+}
+
+// WSFeeds parses the comma separated WSURL into one or more redundant upstream websocket feeds.
+// WSAPIKEY is either a single api key used for all feeds or a comma separated list of api keys matching each url by position.
+func (c Config) WSFeeds() ([]WSFeed, error) {
+	return parseEndpoints("WS", c.WSURL, c.WSAPIKEY)
+}
+
+func parseEndpoints(prefix string, rawURLs string, rawAPIKeys string) ([]Endpoint, error) {
+	urls := strings.Split(rawURLs, ",")
+	apiKeys := strings.Split(rawAPIKeys, ",")
+
+	if len(apiKeys) != 1 && len(apiKeys) != len(urls) {
+		return nil, errors.Errorf("invalid %sAPIKEY: expected 1 or %d api keys, got %d", prefix, len(urls), len(apiKeys))
+	}
+
+	endpoints := make([]Endpoint, 0, len(urls))
+	for i, rawURL := range urls {
+		u, err := url.Parse(strings.TrimSpace(rawURL))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse %sURL: %s", prefix, rawURL)
+		}
+
+		apiKey := apiKeys[0]
+		if len(apiKeys) > 1 {
+			apiKey = apiKeys[i]
+		}
+
+		endpoints = append(endpoints, Endpoint{Name: u.Host, URL: u, APIKEY: strings.TrimSpace(apiKey)})
+	}
+
+	return endpoints, nil
+}
+
 type HTTPClient struct {
-	ctx      context.Context
 	Denom    string
 	Encoding interface{}
 	LCD      *resty.Client
@@ -41,77 +94,73 @@ type HTTPClient struct {
 
 type APIClient interface {
 	// Account
-	GetAccount(address string) (*AccountResponse, error)
-	GetBalance(address string, baseDenom string) (*BalanceResponse, error)
-	GetDelegations(address string, apr *big.Float) ([]Delegation, error)
-	GetRedelegations(address string, apr *big.Float) ([]Redelegation, error)
-	GetUnbondings(address string, baseDenom string, apr *big.Float) ([]Unbonding, error)
-	GetRewards(address string, apr *big.Float) ([]Reward, error)
+	GetAccount(ctx context.Context, address string) (*AccountResponse, error)
+	GetBalance(ctx context.Context, address string, baseDenom string) (*BalanceResponse, error)
+	GetDelegations(ctx context.Context, address string, apr *big.Float) ([]Delegation, error)
+	GetRedelegations(ctx context.Context, address string, apr *big.Float) ([]Redelegation, error)
+	GetUnbondings(ctx context.Context, address string, baseDenom string, apr *big.Float) ([]Unbonding, error)
+	GetRewards(ctx context.Context, address string, apr *big.Float) ([]Reward, error)
 
 	// Bank
-	GetTotalSupply(denom string) (string, error)
-	GetAnnualProvisions() (string, error)
-	GetCommunityTax() (string, error)
-	GetBondedTokens() (string, error)
+	GetTotalSupply(ctx context.Context, denom string) (string, error)
+	GetAnnualProvisions(ctx context.Context) (string, error)
+	GetCommunityTax(ctx context.Context) (string, error)
+	GetBondedTokens(ctx context.Context) (string, error)
 
 	// Block
-	GetBlock(height *int) (*ResultBlock, error)
-	BlockResults(height int) (BlockResults, error)
+	GetBlock(ctx context.Context, height *int) (*ResultBlock, error)
+	BlockResults(ctx context.Context, height int) (BlockResults, error)
 
 	// Fees/Gas
-	GetEstimateGas(rawTx string) (string, error)
+	GetEstimateGas(ctx context.Context, rawTx string) (string, error)
 
 	// Staking
-	GetValidators(apr *big.Float, cursor string, pageSize int) (*ValidatorsResponse, error)
-	GetValidator(addr string, apr *big.Float) (*Validator, error)
+	GetValidators(ctx context.Context, apr *big.Float, cursor string, pageSize int) (*ValidatorsResponse, error)
+	GetValidator(ctx context.Context, addr string, apr *big.Float) (*Validator, error)
 
 	// Transactions
-	GetTxHistory(address string, cursor string, pageSize int, sources map[string]*TxState) (*TxHistoryResponse, error)
-	BroadcastTx(rawTx string) (string, error)
+	GetTxHistory(ctx context.Context, address string, cursor string, pageSize int, sources map[string]*TxState) (*TxHistoryResponse, error)
+	BroadcastTx(ctx context.Context, rawTx string) (string, error)
+	GetUnconfirmedTxs(ctx context.Context, limit int) ([][]byte, error)
+
+	// Health
+	CheckLCD(ctx context.Context) error
+	CheckRPC(ctx context.Context) error
+
+	// Lifecycle
+	Close()
 }
 
-func NewHTTPClient(conf Config) (*HTTPClient, error) {
-	lcdURL, err := url.Parse(conf.LCDURL)
+// NewHTTPClient creates lcd and rpc clients which route each request to the most preferred healthy upstream endpoint
+// according to the timeout, retry and circuit breaker policy of each client loaded from the environment
+func NewHTTPClient(conf Config, prometheus *metrics.Prometheus) (*HTTPClient, error) {
+	lcdEndpoints, err := conf.LCDEndpoints()
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to parse LCDURL: %s", conf.LCDURL)
+		return nil, errors.Wrap(err, "failed to parse lcd endpoints")
 	}
 
-	rpcURL, err := url.Parse(conf.RPCURL)
+	rpcEndpoints, err := conf.RPCEndpoints()
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to parse RPCURL: %s", conf.RPCURL)
+		return nil, errors.Wrap(err, "failed to parse rpc endpoints")
 	}
 
-	lcdHeaders := map[string]string{"Content-Type": "application/json"}
-	if conf.LCDAPIKEY != "" {
-		isLiquify := strings.Contains(conf.LCDURL, "liquify")
-		if isLiquify {
-			lcdURL.Path = path.Join(lcdURL.Path, fmt.Sprintf("api=%s", conf.LCDAPIKEY))
-		}
-
-		isNownodes := strings.Contains(conf.LCDURL, "nownodes")
-		if isNownodes {
-			lcdHeaders["Authorization"] = fmt.Sprintf("Basic %s", conf.LCDAPIKEY)
-		}
+	lcd, err := newUpstreamClient("lcd", lcdEndpoints, "/cosmos/base/tendermint/v1beta1/blocks/latest", lcdHeight, prometheus)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create lcd client")
 	}
 
-	rpcHeaders := map[string]string{"Content-Type": "application/json"}
-	if conf.RPCAPIKEY != "" {
-		isLiquify := strings.Contains(conf.RPCURL, "liquify")
-		if isLiquify {
-			rpcURL.Path = path.Join(rpcURL.Path, fmt.Sprintf("api=%s", conf.RPCAPIKEY))
-		}
-
-		isNownodes := strings.Contains(conf.RPCURL, "nownodes")
-		if isNownodes {
-			rpcHeaders["Authorization"] = fmt.Sprintf("Basic %s", conf.RPCAPIKEY)
-		}
+	rpc, err := newUpstreamClient("rpc", rpcEndpoints, "/status", rpcHeight, prometheus)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create rpc client")
 	}
 
-	lcd := resty.New().SetBaseURL(lcdURL.String()).SetHeaders(lcdHeaders)
-	rpc := resty.New().SetBaseURL(rpcURL.String()).SetHeaders(rpcHeaders)
+	// credentials of each upstream are applied by the client as requests are routed
+	headers := map[string]string{"Content-Type": "application/json"}
+
+	lcd.SetHeaders(headers)
+	rpc.SetHeaders(headers)
 
 	c := &HTTPClient{
-		ctx:      context.Background(),
 		Denom:    conf.Denom,
 		Encoding: conf.Encoding,
 		LCD:      lcd,
@@ -120,3 +169,12 @@ func NewHTTPClient(conf Config) (*HTTPClient, error) {
 
 	return c, nil
 }
+
+// Close stops the background status polling of the lcd and rpc upstreams
+func (c *HTTPClient) Close() {
+	for _, client := range []*resty.Client{c.LCD, c.RPC} {
+		if pool, ok := client.GetClient().Transport.(*upstreamPool); ok {
+			pool.Close()
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package cosmossdk
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// max number of missed blocks backfilled after a gap, any older blocks are skipped
+	maxBackfillBlocks  = 500
+	backfillAttempts   = 3
+	backfillRetryDelay = time.Second
+	// number of recent blocks txs are remembered for to deduplicate txs received from multiple feeds
+	txDedupeBlocks = 100
+)
+
+// FeedTracker deduplicates the blocks and txs received from multiple redundant upstream websocket feeds
+// and detects any blocks missed while disconnected from all feeds
+type FeedTracker struct {
+	lastHeight int
+	m          sync.RWMutex
+	seenTxs    map[int]map[string]struct{}
+}
+
+func NewFeedTracker() *FeedTracker {
+	return &FeedTracker{
+		seenTxs: make(map[int]map[string]struct{}),
+	}
+}
+
+// TrackHeight records the height of a new block received from any feed, returning whether the block is the first delivery
+// of its height along with the range of any heights missed since the previous new block
+func (t *FeedTracker) TrackHeight(height int) (first bool, gap bool, from int, to int) {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	last := t.lastHeight
+	if height <= last {
+		return false, false, 0, 0
+	}
+
+	t.lastHeight = height
+
+	// forget txs older than the dedupe window
+	for h := range t.seenTxs {
+		if h <= height-txDedupeBlocks {
+			delete(t.seenTxs, h)
+		}
+	}
+
+	if last == 0 || height == last+1 {
+		return true, false, 0, 0
+	}
+
+	return true, true, last + 1, height - 1
+}
+
+// TrackTx records a tx received from any feed or backfill, returning false if the tx was already received
+func (t *FeedTracker) TrackTx(height int, rawTx []byte) bool {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	txid := TxID(rawTx)
+
+	if _, ok := t.seenTxs[height]; !ok {
+		t.seenTxs[height] = make(map[string]struct{})
+	}
+
+	if _, ok := t.seenTxs[height][txid]; ok {
+		return false
+	}
+
+	t.seenTxs[height][txid] = struct{}{}
+
+	return true
+}
+
+// IsStale returns true if the height is older than the dedupe window, in which case any txs are assumed to have
+// already been received from another feed as they can no longer be deduplicated
+func (t *FeedTracker) IsStale(height int) bool {
+	t.m.RLock()
+	defer t.m.RUnlock()
+
+	return t.lastHeight != 0 && height <= t.lastHeight-txDedupeBlocks
+}
+
+// BackfillBlockFunc publishes the txs of a single missed block
+type BackfillBlockFunc = func(ctx context.Context, height int) error
+
+// Backfill calls fn for each missed block within the range of heights provided (inclusive), retrying any failed blocks.
+// Only the most recent blocks are backfilled if the gap exceeds the max backfill.
+func Backfill(ctx context.Context, from int, to int, fn BackfillBlockFunc) {
+	if to-from+1 > maxBackfillBlocks {
+		logger.Warnf("skipping backfill of blocks %d to %d: gap exceeds max backfill of %d blocks", from, to-maxBackfillBlocks, maxBackfillBlocks)
+		from = to - maxBackfillBlocks + 1
+	}
+
+	logger.Infof("backfilling missed blocks %d to %d", from, to)
+
+	for height := from; height <= to; height++ {
+		var err error
+		for attempt := 1; attempt <= backfillAttempts; attempt++ {
+			if err = fn(ctx, height); err == nil || attempt == backfillAttempts {
+				break
+			}
+
+			time.Sleep(backfillRetryDelay)
+		}
+
+		if err != nil {
+			logger.Errorf("failed to backfill block: %d: %+v", height, err)
+		}
+	}
+}
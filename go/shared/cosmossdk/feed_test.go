@@ -0,0 +1,202 @@
+package cosmossdk
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestTrackHeightGap(t *testing.T) {
+	tracker := NewFeedTracker()
+
+	tests := []struct {
+		name   string
+		height int
+		first  bool
+		gap    bool
+		from   int
+		to     int
+	}{
+		{name: "first block", height: 100, first: true},
+		{name: "next block", height: 101, first: true},
+		{name: "duplicate block", height: 101, first: false},
+		{name: "older block", height: 99, first: false},
+		{name: "missed blocks", height: 105, first: true, gap: true, from: 102, to: 104},
+		{name: "block after gap", height: 106, first: true},
+	}
+
+	for _, tt := range tests {
+		first, gap, from, to := tracker.TrackHeight(tt.height)
+		if first != tt.first || gap != tt.gap || from != tt.from || to != tt.to {
+			t.Fatalf("%s: expected %t %t %d %d, got %t %t %d %d", tt.name, tt.first, tt.gap, tt.from, tt.to, first, gap, from, to)
+		}
+	}
+}
+
+// backfilled records the heights backfilled, failing each height the number of times provided before succeeding
+type backfilled struct {
+	failures map[int]int
+	heights  []int
+	m        sync.Mutex
+}
+
+func (b *backfilled) backfillBlock(ctx context.Context, height int) error {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	b.heights = append(b.heights, height)
+
+	if b.failures[height] > 0 {
+		b.failures[height]--
+		return errors.Errorf("failed to get block: %d", height)
+	}
+
+	return nil
+}
+
+func TestBackfill(t *testing.T) {
+	b := &backfilled{}
+
+	Backfill(context.Background(), 102, 104, b.backfillBlock)
+
+	if expected := []int{102, 103, 104}; !reflect.DeepEqual(b.heights, expected) {
+		t.Fatalf("expected heights %v, got %v", expected, b.heights)
+	}
+}
+
+func TestBackfillMaxBlocks(t *testing.T) {
+	b := &backfilled{}
+
+	// only the most recent blocks are backfilled
+	Backfill(context.Background(), 1, maxBackfillBlocks+100, b.backfillBlock)
+
+	if len(b.heights) != maxBackfillBlocks {
+		t.Fatalf("expected %d heights, got %d", maxBackfillBlocks, len(b.heights))
+	}
+
+	if first, last := b.heights[0], b.heights[len(b.heights)-1]; first != 101 || last != maxBackfillBlocks+100 {
+		t.Fatalf("expected heights 101 to %d, got %d to %d", maxBackfillBlocks+100, first, last)
+	}
+}
+
+func TestBackfillRetry(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits for the backfill retry delay")
+	}
+
+	// the first block succeeds on retry and the second block fails every attempt without stopping the backfill
+	b := &backfilled{failures: map[int]int{102: 1, 103: backfillAttempts}}
+
+	Backfill(context.Background(), 102, 104, b.backfillBlock)
+
+	expected := []int{102, 102}
+	for i := 0; i < backfillAttempts; i++ {
+		expected = append(expected, 103)
+	}
+	expected = append(expected, 104)
+
+	if !reflect.DeepEqual(b.heights, expected) {
+		t.Fatalf("expected heights %v, got %v", expected, b.heights)
+	}
+}
+
+func TestTrackTxDedupe(t *testing.T) {
+	tracker := NewFeedTracker()
+	tracker.TrackHeight(100)
+
+	tx1 := []byte("tx1")
+	tx2 := []byte("tx2")
+
+	if !tracker.TrackTx(100, tx1) {
+		t.Fatal("expected first delivery of tx to be tracked")
+	}
+
+	// the same tx received from another feed is a duplicate
+	if tracker.TrackTx(100, tx1) {
+		t.Fatal("expected duplicate tx to be rejected")
+	}
+
+	if !tracker.TrackTx(100, tx2) {
+		t.Fatal("expected another tx in the same block to be tracked")
+	}
+
+	// txs are deduplicated per height
+	if !tracker.TrackTx(101, tx1) {
+		t.Fatal("expected tx at another height to be tracked")
+	}
+}
+
+func TestTrackTxDedupeWindow(t *testing.T) {
+	tracker := NewFeedTracker()
+	tracker.TrackHeight(100)
+
+	if tracker.IsStale(100) {
+		t.Fatal("expected current height not to be stale")
+	}
+
+	if !tracker.TrackTx(100, []byte("tx1")) {
+		t.Fatal("expected tx to be tracked")
+	}
+
+	tracker.TrackHeight(100 + txDedupeBlocks - 1)
+
+	if tracker.IsStale(100) {
+		t.Fatal("expected height within the dedupe window not to be stale")
+	}
+
+	if tracker.TrackTx(100, []byte("tx1")) {
+		t.Fatal("expected tx within the dedupe window to be rejected")
+	}
+
+	// txs are forgotten once outside of the dedupe window, with the height reported as stale so late deliveries are dropped
+	tracker.TrackHeight(100 + txDedupeBlocks)
+
+	if !tracker.IsStale(100) {
+		t.Fatal("expected height outside of the dedupe window to be stale")
+	}
+
+	if _, ok := tracker.seenTxs[100]; ok {
+		t.Fatal("expected txs outside of the dedupe window to be forgotten")
+	}
+}
+
+func TestTrackTxConcurrentFeeds(t *testing.T) {
+	tracker := NewFeedTracker()
+	tracker.TrackHeight(100)
+
+	feeds := 4
+	txs := 100
+
+	var tracked sync.Map
+	wg := sync.WaitGroup{}
+
+	// every feed receives the same txs, each of which must be tracked exactly once
+	for f := 0; f < feeds; f++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < txs; i++ {
+				if tracker.TrackTx(100, []byte{byte(i)}) {
+					if _, loaded := tracked.LoadOrStore(i, struct{}{}); loaded {
+						t.Errorf("tx %d tracked more than once", i)
+					}
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	count := 0
+	tracked.Range(func(_, _ interface{}) bool {
+		count++
+		return true
+	})
+
+	if count != txs {
+		t.Fatalf("expected %d txs tracked, got %d", txs, count)
+	}
+}
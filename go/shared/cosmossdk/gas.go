@@ -1,6 +1,7 @@
 package cosmossdk
 
 import (
+	"context"
 	"encoding/base64"
 
 	"github.com/pkg/errors"
@@ -10,7 +11,7 @@ type SimulateRequest struct {
 	TxBytes []byte `json:"tx_bytes"`
 }
 
-func (c *HTTPClient) GetEstimateGas(rawTx string) (string, error) {
+func (c *HTTPClient) GetEstimateGas(ctx context.Context, rawTx string) (string, error) {
 	txBytes, err := base64.StdEncoding.DecodeString(rawTx)
 	if err != nil {
 		return "", errors.Wrapf(err, "failed to decode rawTx: %s", rawTx)
@@ -24,7 +25,7 @@ func (c *HTTPClient) GetEstimateGas(rawTx string) (string, error) {
 
 	e := &ErrorResponse{}
 
-	r, err := c.LCD.R().SetBody(SimulateRequest{TxBytes: txBytes}).SetResult(res).SetError(e).Post("/cosmos/tx/v1beta1/simulate")
+	r, err := c.LCD.R().SetContext(ctx).SetBody(SimulateRequest{TxBytes: txBytes}).SetResult(res).SetError(e).Post("/cosmos/tx/v1beta1/simulate")
 	if err != nil {
 		return "", errors.Wrap(err, "failed to estimate gas")
 	}
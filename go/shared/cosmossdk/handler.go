@@ -1,7 +1,9 @@
 package cosmossdk
 
 import (
+	"context"
 	"math/big"
+	"net/http"
 
 	ws "github.com/gorilla/websocket"
 	"github.com/shapeshift/unchained/shared/api"
@@ -13,15 +15,17 @@ type RouteHandler interface {
 	// WS
 	StartWebsocket() error
 	StopWebsocket()
+	StopUpstreams()
 	NewWebsocketConnection(conn *ws.Conn, manager *websocket.Manager)
+	NewStreamConnection(w http.ResponseWriter, r *http.Request, pubkey string, manager *websocket.Manager)
 
 	// REST
-	GetInfo() (api.Info, error)
-	GetAccount(pubkey string) (api.Account, error)
-	GetTxHistory(pubkey string, cursor string, pageSize int) (api.TxHistory, error)
-	GetTx(txid string) (api.Tx, error)
-	SendTx(hex string) (string, error)
-	EstimateGas(rawTx string) (string, error)
+	GetInfo(ctx context.Context) (api.Info, error)
+	GetAccount(ctx context.Context, pubkey string) (api.Account, error)
+	GetTxHistory(ctx context.Context, pubkey string, cursor string, pageSize int) (api.TxHistory, error)
+	GetTx(ctx context.Context, txid string) (api.Tx, error)
+	SendTx(ctx context.Context, hex string) (string, error)
+	EstimateGas(ctx context.Context, rawTx string) (string, error)
 }
 
 type Handler struct {
@@ -31,7 +35,7 @@ type Handler struct {
 	NativeFee    int
 }
 
-func (h *Handler) GetInfo() (api.Info, error) {
+func (h *Handler) GetInfo(ctx context.Context) (api.Info, error) {
 	info := Info{
 		BaseInfo: api.BaseInfo{
 			Network: "mainnet",
@@ -41,13 +45,13 @@ func (h *Handler) GetInfo() (api.Info, error) {
 	return info, nil
 }
 
-func (h *Handler) GetAccount(pubkey string) (api.Account, error) {
+func (h *Handler) GetAccount(ctx context.Context, pubkey string) (api.Account, error) {
 	account := Account{}
 
-	g := new(errgroup.Group)
+	g, ctx := errgroup.WithContext(ctx)
 
 	g.Go(func() error {
-		a, err := h.HTTPClient.GetAccount(pubkey)
+		a, err := h.HTTPClient.GetAccount(ctx, pubkey)
 		if err != nil {
 			return err
 		}
@@ -60,7 +64,7 @@ func (h *Handler) GetAccount(pubkey string) (api.Account, error) {
 	})
 
 	g.Go(func() error {
-		b, err := h.HTTPClient.GetBalance(pubkey, h.Denom)
+		b, err := h.HTTPClient.GetBalance(ctx, pubkey, h.Denom)
 		if err != nil {
 			return err
 		}
@@ -79,21 +83,26 @@ func (h *Handler) GetAccount(pubkey string) (api.Account, error) {
 	return account, nil
 }
 
-func (h *Handler) SendTx(hex string) (string, error) {
-	return h.HTTPClient.BroadcastTx(hex)
+// StopUpstreams stops the background status polling of the upstream nodes
+func (h *Handler) StopUpstreams() {
+	h.HTTPClient.Close()
 }
 
-func (h Handler) EstimateGas(rawTx string) (string, error) {
-	return h.HTTPClient.GetEstimateGas(rawTx)
+func (h *Handler) SendTx(ctx context.Context, hex string) (string, error) {
+	return h.HTTPClient.BroadcastTx(ctx, hex)
 }
 
-func (h *Handler) GetStaking(pubkey string, apr *big.Float) (*Staking, error) {
+func (h Handler) EstimateGas(ctx context.Context, rawTx string) (string, error) {
+	return h.HTTPClient.GetEstimateGas(ctx, rawTx)
+}
+
+func (h *Handler) GetStaking(ctx context.Context, pubkey string, apr *big.Float) (*Staking, error) {
 	staking := &Staking{}
 
-	g := new(errgroup.Group)
+	g, ctx := errgroup.WithContext(ctx)
 
 	g.Go(func() error {
-		delegations, err := h.HTTPClient.GetDelegations(pubkey, apr)
+		delegations, err := h.HTTPClient.GetDelegations(ctx, pubkey, apr)
 		if err != nil {
 			return err
 		}
@@ -103,7 +112,7 @@ func (h *Handler) GetStaking(pubkey string, apr *big.Float) (*Staking, error) {
 	})
 
 	g.Go(func() error {
-		redelegations, err := h.HTTPClient.GetRedelegations(pubkey, apr)
+		redelegations, err := h.HTTPClient.GetRedelegations(ctx, pubkey, apr)
 		if err != nil {
 			return err
 		}
@@ -113,7 +122,7 @@ func (h *Handler) GetStaking(pubkey string, apr *big.Float) (*Staking, error) {
 	})
 
 	g.Go(func() error {
-		unbondings, err := h.HTTPClient.GetUnbondings(pubkey, h.Denom, apr)
+		unbondings, err := h.HTTPClient.GetUnbondings(ctx, pubkey, h.Denom, apr)
 		if err != nil {
 			return err
 		}
@@ -123,7 +132,7 @@ func (h *Handler) GetStaking(pubkey string, apr *big.Float) (*Staking, error) {
 	})
 
 	g.Go(func() error {
-		rewards, err := h.HTTPClient.GetRewards(pubkey, apr)
+		rewards, err := h.HTTPClient.GetRewards(ctx, pubkey, apr)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,105 @@
+package cosmossdk
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shapeshift/unchained/shared/api"
+)
+
+// max age of the latest block before the coinstack is considered unready (chain halted or block feed stalled)
+const DEFAULT_MAX_BLOCK_AGE = 60 * time.Second
+
+func MaxBlockAgeFromEnv() time.Duration {
+	value := os.Getenv("MAX_BLOCK_AGE")
+	if value == "" {
+		return DEFAULT_MAX_BLOCK_AGE
+	}
+
+	if age, err := time.ParseDuration(value); err == nil && age > 0 {
+		return age
+	}
+
+	logger.Warnf("invalid MAX_BLOCK_AGE: %s (defaulting to %s)", value, DEFAULT_MAX_BLOCK_AGE)
+
+	return DEFAULT_MAX_BLOCK_AGE
+}
+
+// CheckLCD verifies the lcd node is reachable and not syncing
+func (c *HTTPClient) CheckLCD(ctx context.Context) error {
+	var res struct {
+		Syncing bool `json:"syncing"`
+	}
+
+	r, err := c.LCD.R().SetContext(ctx).SetResult(&res).Get("/cosmos/base/tendermint/v1beta1/syncing")
+	if err != nil {
+		return errors.Wrap(err, "failed to get lcd sync status")
+	}
+
+	if r.IsError() {
+		return errors.Errorf("failed to get lcd sync status: %s", r.Status())
+	}
+
+	if res.Syncing {
+		return errors.New("lcd node is syncing")
+	}
+
+	return nil
+}
+
+// CheckRPC verifies the rpc node is reachable and reports itself healthy
+func (c *HTTPClient) CheckRPC(ctx context.Context) error {
+	var res struct {
+		Error *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+			Data    string `json:"data"`
+		} `json:"error"`
+	}
+
+	r, err := c.RPC.R().SetContext(ctx).SetResult(&res).SetError(&res).Get("/health")
+	if err != nil {
+		return errors.Wrap(err, "failed to get rpc health")
+	}
+
+	if res.Error != nil {
+		return errors.Errorf("failed to get rpc health: %s: %s", res.Error.Message, res.Error.Data)
+	}
+
+	if r.IsError() {
+		return errors.Errorf("failed to get rpc health: %s", r.Status())
+	}
+
+	return nil
+}
+
+// CheckLatestBlock verifies the latest block was produced within maxAge of the current time
+func (s *BlockService) CheckLatestBlock(maxAge time.Duration) error {
+	s.m.RLock()
+	latest := s.Latest
+	s.m.RUnlock()
+
+	if latest == nil {
+		return errors.New("no latest block")
+	}
+
+	age := time.Since(time.Unix(int64(latest.Timestamp), 0)).Truncate(time.Second)
+	if age > maxAge {
+		return errors.Errorf("latest block %d is %s old (max: %s)", latest.Height, age, maxAge)
+	}
+
+	return nil
+}
+
+// HealthChecks returns the readiness checks of the upstream nodes and latest block
+func (h *Handler) HealthChecks() []api.HealthCheck {
+	maxBlockAge := MaxBlockAgeFromEnv()
+
+	return []api.HealthCheck{
+		{Name: "lcd", Check: h.HTTPClient.CheckLCD},
+		{Name: "rpc", Check: h.HTTPClient.CheckRPC},
+		{Name: "block", Check: func(context.Context) error { return h.BlockService.CheckLatestBlock(maxBlockAge) }},
+	}
+}
@@ -0,0 +1,80 @@
+package cosmossdk
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/shapeshift/unchained/shared/api"
+)
+
+func newTestBlockService(age time.Duration) *BlockService {
+	s := &BlockService{Blocks: make(map[int]*BlockResponse)}
+	s.WriteBlock(&BlockResponse{Height: 100, Hash: "hash", Timestamp: int(time.Now().Add(-age).Unix())}, true)
+	return s
+}
+
+func TestCheckLatestBlock(t *testing.T) {
+	if err := newTestBlockService(time.Second).CheckLatestBlock(time.Minute); err != nil {
+		t.Errorf("expected recent block to be healthy: %v", err)
+	}
+
+	if err := newTestBlockService(2 * time.Minute).CheckLatestBlock(time.Minute); err == nil {
+		t.Error("expected stale block to be unhealthy")
+	}
+
+	if err := (&BlockService{Blocks: make(map[int]*BlockResponse)}).CheckLatestBlock(time.Minute); err == nil {
+		t.Error("expected missing latest block to be unhealthy")
+	}
+}
+
+func TestReadinessStaleBlock(t *testing.T) {
+	s := newTestBlockService(2 * time.Minute)
+
+	health := api.NewHealth("test",
+		api.HealthCheck{Name: "rpc", Check: func(context.Context) error { return nil }},
+		api.HealthCheck{Name: "block", Check: func(context.Context) error { return s.CheckLatestBlock(time.Minute) }},
+	)
+
+	ready := func() (int, api.HealthStatus) {
+		w := httptest.NewRecorder()
+		health.Ready(w, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+
+		status := api.HealthStatus{}
+		if err := json.Unmarshal(w.Body.Bytes(), &status); err != nil {
+			t.Fatalf("failed to unmarshal health status: %+v", err)
+		}
+
+		return w.Code, status
+	}
+
+	code, status := ready()
+	if code != http.StatusServiceUnavailable || status.Status != api.HealthStatusDown {
+		t.Fatalf("expected stale block to fail readiness, got %d %s", code, status.Status)
+	}
+
+	if status.Checks["block"].Status != api.HealthStatusDown || status.Checks["block"].Error == "" {
+		t.Errorf("expected block check to be down with an error: %+v", status.Checks["block"])
+	}
+
+	if status.Checks["rpc"].Status != api.HealthStatusUp {
+		t.Errorf("expected rpc check to be up: %+v", status.Checks["rpc"])
+	}
+
+	// liveness is unaffected by the stale block
+	w := httptest.NewRecorder()
+	health.Live(w, httptest.NewRequest(http.MethodGet, "/health/live", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected liveness to pass, got %d", w.Code)
+	}
+
+	// readiness recovers once a new block is received
+	s.WriteBlock(&BlockResponse{Height: 101, Hash: "hash", Timestamp: int(time.Now().Unix())}, true)
+
+	if code, status := ready(); code != http.StatusOK || status.Status != api.HealthStatusUp {
+		t.Fatalf("expected new block to pass readiness, got %d %s", code, status.Status)
+	}
+}
@@ -1,11 +1,13 @@
 package cosmossdk
 
 import (
+	"context"
+
 	"github.com/pkg/errors"
 	"golang.org/x/sync/errgroup"
 )
 
-type RequestFn = func(string, int, int) ([]HistoryTx, error)
+type RequestFn = func(context.Context, string, int, int) ([]HistoryTx, error)
 
 // TxState stores state for a specific query source
 type TxState struct {
@@ -32,9 +34,9 @@ type History struct {
 	State    map[string]*TxState
 }
 
-func (h *History) doRequest(txState *TxState) ([]HistoryTx, error) {
+func (h *History) doRequest(ctx context.Context, txState *TxState) ([]HistoryTx, error) {
 	for {
-		txs, err := txState.request(txState.query, txState.Page, h.PageSize)
+		txs, err := txState.request(ctx, txState.query, txState.Page, h.PageSize)
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to do request")
 		}
@@ -100,11 +102,11 @@ func (h *History) filterByCursor(txs []HistoryTx) ([]HistoryTx, error) {
 	return filtered, nil
 }
 
-func (h *History) Get() (*TxHistoryResponse, error) {
+func (h *History) Get(ctx context.Context) (*TxHistoryResponse, error) {
 	txs := []Tx{}
 
 	// fetch starting transaction history based on current state of the cursor
-	if err := h.fetch(false); err != nil {
+	if err := h.fetch(ctx, false); err != nil {
 		return nil, errors.Wrap(err, "failed to get tx history")
 	}
 
@@ -115,7 +117,7 @@ func (h *History) Get() (*TxHistoryResponse, error) {
 	// splice together transactions in the correct order until we either run out of transactions to return or fill a full page response.
 	for len(txs) < h.PageSize {
 		// fetch more transaction history if we have run out and more are available
-		if err := h.fetch(true); err != nil {
+		if err := h.fetch(ctx, true); err != nil {
 			return nil, errors.Wrap(err, "failed to get additional tx history")
 		}
 
@@ -123,7 +125,7 @@ func (h *History) Get() (*TxHistoryResponse, error) {
 			break
 		}
 
-		tx, err := h.getNextTx()
+		tx, err := h.getNextTx(ctx)
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to get next tx")
 		}
@@ -163,8 +165,9 @@ func (h *History) Get() (*TxHistoryResponse, error) {
 	return txHistory, nil
 }
 
-func (h *History) fetch(more bool) error {
-	g := new(errgroup.Group)
+func (h *History) fetch(ctx context.Context, more bool) error {
+	// a failed source cancels any outstanding requests of the other sources
+	g, ctx := errgroup.WithContext(ctx)
 
 	for k, s := range h.State {
 		state := s
@@ -180,7 +183,7 @@ func (h *History) fetch(more bool) error {
 		}
 
 		g.Go(func() error {
-			txs, err := h.doRequest(state)
+			txs, err := h.doRequest(ctx, state)
 			if err != nil {
 				return errors.Wrapf(err, "failed to fetch %s", source)
 			}
@@ -209,7 +212,7 @@ func (h *History) hasTxHistory() bool {
 }
 
 // getNextTx formats and returns the next most recent transaction, removing it from corresponding source txs set
-func (h *History) getNextTx() (*Tx, error) {
+func (h *History) getNextTx(ctx context.Context) (*Tx, error) {
 	var state *TxState
 	var nextHeight int
 
@@ -223,7 +226,7 @@ func (h *History) getNextTx() (*Tx, error) {
 
 	nextTx := state.txs[0]
 
-	tx, err := nextTx.FormatTx()
+	tx, err := nextTx.FormatTx(ctx)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to format transaction: %s", nextTx.GetTxID())
 	}
@@ -0,0 +1,176 @@
+package cosmossdk
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	DEFAULT_MEMPOOL_POLL_INTERVAL = 2 * time.Second
+	// max number of txs returned by the rpc node per request
+	unconfirmedTxsLimit = 100
+)
+
+// MempoolPollIntervalFromEnv loads the optional mempool poll interval from the environment, falling back to the default.
+// A zero interval disables mempool polling.
+func MempoolPollIntervalFromEnv() time.Duration {
+	value := os.Getenv("MEMPOOL_POLL_INTERVAL")
+	if value == "" {
+		return DEFAULT_MEMPOOL_POLL_INTERVAL
+	}
+
+	if interval, err := time.ParseDuration(value); err == nil && interval >= 0 {
+		return interval
+	}
+
+	logger.Warnf("invalid MEMPOOL_POLL_INTERVAL: %s (defaulting to %s)", value, DEFAULT_MEMPOOL_POLL_INTERVAL)
+
+	return DEFAULT_MEMPOOL_POLL_INTERVAL
+}
+
+func (c *HTTPClient) GetUnconfirmedTxs(ctx context.Context, limit int) ([][]byte, error) {
+	var res struct {
+		Result struct {
+			// base64 encoded txs are decoded into raw tx bytes
+			Txs [][]byte `json:"txs"`
+		} `json:"result"`
+		Error *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+			Data    string `json:"data"`
+		} `json:"error"`
+	}
+
+	_, err := c.RPC.R().SetContext(ctx).SetResult(&res).SetError(&res).SetQueryParam("limit", fmt.Sprintf("%d", limit)).Get("/unconfirmed_txs")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get unconfirmed txs")
+	}
+
+	if res.Error != nil {
+		return nil, errors.Errorf("failed to get unconfirmed txs: %s: %s", res.Error.Message, res.Error.Data)
+	}
+
+	return res.Result.Txs, nil
+}
+
+type UnconfirmedTxsFetcher interface {
+	GetUnconfirmedTxs(ctx context.Context, limit int) ([][]byte, error)
+}
+
+// PendingTxFunc is called once for each new tx detected in the mempool
+type PendingTxFunc = func(rawTx []byte)
+
+// MempoolWatcher polls the mempool of the rpc node for pending txs.
+// Each tx is reported once while pending and never after it has been confirmed.
+type MempoolWatcher struct {
+	done     chan struct{}
+	fetcher  UnconfirmedTxsFetcher
+	interval time.Duration
+	m        sync.Mutex
+	// txids of pending txs already reported or confirmed txs not to be reported
+	seen     map[string]struct{}
+	started  bool
+	stopOnce sync.Once
+}
+
+func NewMempoolWatcher(fetcher UnconfirmedTxsFetcher, interval time.Duration) *MempoolWatcher {
+	return &MempoolWatcher{
+		done:     make(chan struct{}),
+		fetcher:  fetcher,
+		interval: interval,
+		seen:     make(map[string]struct{}),
+	}
+}
+
+// TxID returns the hash of a raw tx as used for the txid
+func TxID(rawTx []byte) string {
+	return fmt.Sprintf("%X", sha256.Sum256(rawTx))
+}
+
+// Start polling the mempool in the background, calling fn for each new pending tx
+func (w *MempoolWatcher) Start(fn PendingTxFunc) {
+	if w.interval == 0 {
+		logger.Info("mempool polling disabled")
+		return
+	}
+
+	w.m.Lock()
+	w.started = true
+	w.m.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-w.done:
+				return
+			case <-ticker.C:
+				if err := w.poll(fn); err != nil {
+					logger.Errorf("failed to poll mempool: %+v", err)
+				}
+			}
+		}
+	}()
+}
+
+func (w *MempoolWatcher) Stop() {
+	w.stopOnce.Do(func() { close(w.done) })
+}
+
+// Confirm marks a tx as confirmed so it is not reported as pending if still returned by a stale mempool request
+func (w *MempoolWatcher) Confirm(rawTx []byte) {
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	// nothing will prune seen txs if not polling
+	if !w.started {
+		return
+	}
+
+	w.seen[TxID(rawTx)] = struct{}{}
+}
+
+func (w *MempoolWatcher) poll(fn PendingTxFunc) error {
+	rawTxs, err := w.fetcher.GetUnconfirmedTxs(context.Background(), unconfirmedTxsLimit)
+	if err != nil {
+		return err
+	}
+
+	pending := make(map[string]struct{}, len(rawTxs))
+	newTxs := [][]byte{}
+
+	w.m.Lock()
+	for _, rawTx := range rawTxs {
+		txid := TxID(rawTx)
+		pending[txid] = struct{}{}
+
+		if _, ok := w.seen[txid]; ok {
+			continue
+		}
+
+		w.seen[txid] = struct{}{}
+		newTxs = append(newTxs, rawTx)
+	}
+
+	// forget any txs no longer in the mempool as they have either been confirmed or dropped
+	for txid := range w.seen {
+		if _, ok := pending[txid]; !ok {
+			delete(w.seen, txid)
+		}
+	}
+	w.m.Unlock()
+
+	for _, rawTx := range newTxs {
+		fn(rawTx)
+	}
+
+	return nil
+}
@@ -34,6 +34,19 @@ type Attribute struct {
 // swagger:model ValueByAttribute
 type ValueByAttribute map[string]string
 
+// Contains info about a block
+// swagger:model Block
+type Block struct {
+	// required: true
+	// example: 1000000
+	Height int `json:"height"`
+	// required: true
+	Hash string `json:"hash"`
+	// required: true
+	// example: 1643052655
+	Timestamp int `json:"timestamp"`
+}
+
 // Contains info about a staking delegation
 // swagger:model Delegation
 type Delegation struct {
@@ -0,0 +1,109 @@
+package cosmossdk
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/pkg/errors"
+	"github.com/shapeshift/unchained/shared/config"
+	"github.com/shapeshift/unchained/shared/log"
+	"github.com/shapeshift/unchained/shared/metrics"
+)
+
+const (
+	DEFAULT_UPSTREAM_TIMEOUT           = 10 * time.Second
+	DEFAULT_UPSTREAM_RETRIES           = 2
+	DEFAULT_UPSTREAM_RETRY_WAIT        = 100 * time.Millisecond
+	DEFAULT_UPSTREAM_RETRY_MAX_WAIT    = 2 * time.Second
+	DEFAULT_UPSTREAM_BREAKER_THRESHOLD = 5
+	DEFAULT_UPSTREAM_BREAKER_COOLDOWN  = 30 * time.Second
+)
+
+// errCircuitOpen is returned without being retried if the circuit of every upstream is open
+var errCircuitOpen = errors.New("circuit open")
+
+// Policy for requests made to a class of upstream endpoints (ex. lcd, rpc or indexer)
+type Policy struct {
+	// timeout of each attempt to an upstream, allowing a hung upstream to be failed over
+	Timeout time.Duration
+	// max number of times an idempotent request is retried if all upstreams are unavailable, requests with side effects are never retried
+	Retries int
+	// min wait before a retry, backing off exponentially with jitter for each subsequent retry
+	RetryWait time.Duration
+	// max wait before a retry
+	RetryMaxWait time.Duration
+	// number of consecutive failures before the circuit of an upstream is opened and requests fail fast
+	BreakerThreshold int
+	// time the circuit of an upstream stays open before a single trial request is allowed through
+	BreakerCooldown time.Duration
+}
+
+// PolicyFromEnv loads any optional policy config for the class of upstream endpoints from the environment, falling back to defaults.
+// Each value is prefixed with the class name (ex. LCD_TIMEOUT, RPC_RETRIES or INDEXER_BREAKER_THRESHOLD).
+func PolicyFromEnv(class string) Policy {
+	prefix := strings.ToUpper(class)
+
+	return Policy{
+		Timeout:          config.DurationFromEnv(prefix+"_TIMEOUT", DEFAULT_UPSTREAM_TIMEOUT),
+		Retries:          config.IntFromEnv(prefix+"_RETRIES", DEFAULT_UPSTREAM_RETRIES, 0),
+		RetryWait:        config.DurationFromEnv(prefix+"_RETRY_WAIT", DEFAULT_UPSTREAM_RETRY_WAIT),
+		RetryMaxWait:     config.DurationFromEnv(prefix+"_RETRY_MAX_WAIT", DEFAULT_UPSTREAM_RETRY_MAX_WAIT),
+		BreakerThreshold: config.IntFromEnv(prefix+"_BREAKER_THRESHOLD", DEFAULT_UPSTREAM_BREAKER_THRESHOLD, 1),
+		BreakerCooldown:  config.DurationFromEnv(prefix+"_BREAKER_COOLDOWN", DEFAULT_UPSTREAM_BREAKER_COOLDOWN),
+	}
+}
+
+// NewUpstreamClient creates a client for the class of upstream endpoints provided in order of preference.
+// Each request is routed to the most preferred healthy upstream according to the policy of the class loaded from the environment.
+func NewUpstreamClient(class string, endpoints []Endpoint, prometheus *metrics.Prometheus) (*resty.Client, error) {
+	return newUpstreamClient(class, endpoints, "", nil, prometheus)
+}
+
+func newUpstreamClient(class string, endpoints []Endpoint, statusPath string, height heightFunc, prometheus *metrics.Prometheus) (*resty.Client, error) {
+	if len(endpoints) == 0 {
+		return nil, errors.Errorf("no %s endpoints", class)
+	}
+
+	policy := PolicyFromEnv(class)
+
+	pool := newUpstreamPool(class, endpoints, statusPath, height, UpstreamConfigFromEnv(), policy, prometheus)
+
+	client := resty.New().
+		SetBaseURL(pool.primary().baseURL.String()).
+		SetTransport(pool).
+		SetLogger(log.WithFields(log.Fields{"client": class})).
+		SetRetryCount(policy.Retries).
+		SetRetryWaitTime(policy.RetryWait).
+		SetRetryMaxWaitTime(policy.RetryMaxWait).
+		AddRetryCondition(isRetryable)
+
+	return client, nil
+}
+
+// isRetryable returns true if an idempotent request failed because all upstreams were unavailable.
+// Requests with side effects (ex. broadcasting a tx) are never retried as the upstream may have processed the request before failing.
+func isRetryable(res *resty.Response, err error) bool {
+	if res == nil || res.Request == nil {
+		return false
+	}
+
+	if method := res.Request.Method; method != http.MethodGet && method != http.MethodHead {
+		return false
+	}
+
+	if errors.Is(err, errCircuitOpen) {
+		return false
+	}
+
+	if err == nil && res.RawResponse == nil {
+		return false
+	}
+
+	return isUnavailable(res.RawResponse, err)
+}
+
+func circuitOpenError(class string) error {
+	return errors.Wrapf(errCircuitOpen, "all %s upstreams unavailable", class)
+}
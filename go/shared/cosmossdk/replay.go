@@ -0,0 +1,89 @@
+package cosmossdk
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/shapeshift/unchained/shared/api"
+	"github.com/shapeshift/unchained/shared/websocket"
+)
+
+const MAX_REPLAY_TXS = 1000
+
+type TxHistoryFunc = func(ctx context.Context, pubkey string, cursor string, pageSize int) (api.TxHistory, error)
+
+// NewReplayHandler creates a websocket replay handler that backfills any missed transactions using tx history
+func NewReplayHandler(getTxHistory TxHistoryFunc) websocket.ReplayHandlerFunc {
+	return func(ctx context.Context, addr string, fromHeight int, fromTxID string) ([]interface{}, error) {
+		txs, err := ReplayTxs(ctx, getTxHistory, addr, fromHeight, fromTxID)
+		if err != nil {
+			return nil, err
+		}
+
+		data := make([]interface{}, len(txs))
+		for i, tx := range txs {
+			data[i] = tx
+		}
+
+		return data, nil
+	}
+}
+
+// ReplayTxs pages through tx history for an address and returns all transactions at or after fromHeight,
+// or after fromTxID, in chronological order. Paging stops if ctx is cancelled.
+func ReplayTxs(ctx context.Context, getTxHistory TxHistoryFunc, pubkey string, fromHeight int, fromTxID string) ([]Tx, error) {
+	if fromHeight <= 0 && fromTxID == "" {
+		return nil, errors.New("fromHeight or fromTxid required")
+	}
+
+	txs := []Tx{}
+	cursor := ""
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		res, err := getTxHistory(ctx, pubkey, cursor, MAX_PAGE_SIZE_TX_HISTORY)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get tx history for address: %s", pubkey)
+		}
+
+		txHistory, ok := res.(TxHistory)
+		if !ok {
+			return nil, errors.Errorf("unsupported tx history type: %T", res)
+		}
+
+		done := false
+		for _, tx := range txHistory.Txs {
+			if fromTxID != "" && tx.TxID == fromTxID {
+				done = true
+				break
+			}
+
+			if fromHeight > 0 && tx.BlockHeight < fromHeight {
+				done = true
+				break
+			}
+
+			txs = append(txs, tx)
+		}
+
+		if len(txs) > MAX_REPLAY_TXS {
+			return nil, errors.Errorf("more than %d txs to replay for address: %s", MAX_REPLAY_TXS, pubkey)
+		}
+
+		if done || txHistory.Cursor == "" {
+			break
+		}
+
+		cursor = txHistory.Cursor
+	}
+
+	// tx history is returned most recent first, replay oldest first
+	for i, j := 0, len(txs)-1; i < j; i, j = i+1, j-1 {
+		txs[i], txs[j] = txs[j], txs[i]
+	}
+
+	return txs, nil
+}
@@ -1,6 +1,7 @@
 package cosmossdk
 
 import (
+	"context"
 	"fmt"
 	"math/big"
 	"strconv"
@@ -8,7 +9,7 @@ import (
 	"github.com/pkg/errors"
 )
 
-func (c *HTTPClient) GetValidators(apr *big.Float, cursor string, pageSize int) (*ValidatorsResponse, error) {
+func (c *HTTPClient) GetValidators(ctx context.Context, apr *big.Float, cursor string, pageSize int) (*ValidatorsResponse, error) {
 	var res QueryValidatorsResponse
 
 	queryParams := map[string]string{
@@ -16,7 +17,7 @@ func (c *HTTPClient) GetValidators(apr *big.Float, cursor string, pageSize int)
 		"pagination.limit": strconv.Itoa(pageSize),
 	}
 
-	_, err := c.LCD.R().SetResult(&res).SetQueryParams(queryParams).Get("/cosmos/staking/v1beta1/validators")
+	_, err := c.LCD.R().SetContext(ctx).SetResult(&res).SetQueryParams(queryParams).Get("/cosmos/staking/v1beta1/validators")
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get validators")
 	}
@@ -34,10 +35,10 @@ func (c *HTTPClient) GetValidators(apr *big.Float, cursor string, pageSize int)
 	return resp, nil
 }
 
-func (c *HTTPClient) GetValidator(addr string, apr *big.Float) (*Validator, error) {
+func (c *HTTPClient) GetValidator(ctx context.Context, addr string, apr *big.Float) (*Validator, error) {
 	var res QueryValidatorResponse
 
-	_, err := c.LCD.R().SetResult(&res).Get(fmt.Sprintf("/cosmos/staking/v1beta1/validators/%s", addr))
+	_, err := c.LCD.R().SetContext(ctx).SetResult(&res).Get(fmt.Sprintf("/cosmos/staking/v1beta1/validators/%s", addr))
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get validators")
 	}
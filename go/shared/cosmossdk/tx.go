@@ -1,10 +1,45 @@
 package cosmossdk
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
 	"github.com/pkg/errors"
+	"github.com/shapeshift/unchained/shared/api"
 )
 
-func (c *HTTPClient) GetTxHistory(address string, cursor string, pageSize int, sources map[string]*TxState) (*TxHistoryResponse, error) {
+// grpc status code returned by the lcd node if a requested resource does not exist
+const grpcCodeNotFound = 5
+
+// TxExists returns false if the lcd node responds with a not found status (404 or grpc code 5) for the tx,
+// allowing a missing tx to be identified without matching the error message of the rpc node
+func (c *HTTPClient) TxExists(ctx context.Context, txid string) (bool, error) {
+	hash := strings.ToUpper(strings.TrimPrefix(txid, "0x"))
+
+	var res struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+
+	r, err := c.LCD.R().SetContext(ctx).SetError(&res).Get(fmt.Sprintf("/cosmos/tx/v1beta1/txs/%s", hash))
+	if err != nil {
+		return false, api.NewUpstreamError(err, fmt.Sprintf("failed to get tx: %s", hash))
+	}
+
+	if r.StatusCode() == http.StatusNotFound || res.Code == grpcCodeNotFound {
+		return false, nil
+	}
+
+	if r.IsError() {
+		return false, api.NewUpstreamError(errors.Errorf("%s: %s", r.Status(), res.Message), fmt.Sprintf("failed to get tx: %s", hash))
+	}
+
+	return true, nil
+}
+
+func (c *HTTPClient) GetTxHistory(ctx context.Context, address string, cursor string, pageSize int, sources map[string]*TxState) (*TxHistoryResponse, error) {
 	history := &History{
 		Cursor:   &Cursor{State: make(map[string]*CursorState)},
 		PageSize: pageSize,
@@ -28,7 +63,7 @@ func (c *HTTPClient) GetTxHistory(address string, cursor string, pageSize int, s
 		s.Page = history.Cursor.State[source].Page
 	}
 
-	txHistory, err := history.Get()
+	txHistory, err := history.Get(ctx)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to get tx history for address: %s", address)
 	}
@@ -0,0 +1,60 @@
+package cosmossdk
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/shapeshift/unchained/shared/api"
+)
+
+func TestTxExists(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		exists  bool
+		errCode string
+	}{
+		{name: "found", status: http.StatusOK, body: `{"tx":{},"tx_response":{}}`, exists: true},
+		{name: "not found status", status: http.StatusNotFound, body: `{"code":5,"message":"tx not found: ABC"}`},
+		{name: "not found grpc code", status: http.StatusBadRequest, body: `{"code":5,"message":"tx not found: ABC"}`},
+		{name: "upstream failure", status: http.StatusInternalServerError, body: `{"code":13,"message":"internal"}`, errCode: api.ErrorCodeUpstream},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/cosmos/tx/v1beta1/txs/ABC" {
+					t.Errorf("unexpected path: %s", r.URL.Path)
+				}
+
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			c := &HTTPClient{LCD: resty.New().SetBaseURL(server.URL)}
+
+			exists, err := c.TxExists(context.Background(), "0xabc")
+
+			if tt.errCode != "" {
+				if err == nil || api.AsAPIError(err).Code != tt.errCode {
+					t.Fatalf("expected %s error, got: %v", tt.errCode, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+
+			if exists != tt.exists {
+				t.Fatalf("expected exists %t, got %t", tt.exists, exists)
+			}
+		})
+	}
+}
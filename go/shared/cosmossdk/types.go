@@ -1,5 +1,7 @@
 package cosmossdk
 
+import "context"
+
 type AccountResponse struct {
 	Address       string
 	AccountNumber int
@@ -46,7 +48,7 @@ type HistoryTx interface {
 	GetHeight() int64
 	GetIndex() int
 	GetTxID() string
-	FormatTx() (*Tx, error)
+	FormatTx(ctx context.Context) (*Tx, error)
 }
 
 type TxHistoryResponse struct {
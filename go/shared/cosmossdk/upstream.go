@@ -0,0 +1,546 @@
+package cosmossdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shapeshift/unchained/shared/config"
+	"github.com/shapeshift/unchained/shared/metrics"
+	"github.com/shapeshift/unchained/shared/tracing"
+)
+
+const (
+	DEFAULT_UPSTREAM_POLL_INTERVAL  = 10 * time.Second
+	DEFAULT_UPSTREAM_MAX_HEIGHT_LAG = 5
+	DEFAULT_UPSTREAM_MAX_LATENCY    = 10 * time.Second
+)
+
+const (
+	// weight of the latest observation in the moving averages of error rate and latency
+	upstreamAlpha = 0.2
+	// error rate above which an upstream is considered unhealthy
+	upstreamMaxErrorRate = 0.5
+	// block height of the lcd node serving a response, set by the grpc gateway
+	blockHeightHeader = "Grpc-Metadata-X-Cosmos-Block-Height"
+)
+
+// UpstreamConfig for the health tracking of upstream lcd and rpc endpoints
+type UpstreamConfig struct {
+	// interval the block height of each upstream is polled at
+	PollInterval time.Duration
+	// max number of blocks an upstream can be behind the highest upstream before it is considered unhealthy
+	MaxHeightLag int64
+	// max average latency before an upstream is considered unhealthy
+	MaxLatency time.Duration
+}
+
+// UpstreamConfigFromEnv loads any optional upstream config from the environment, falling back to defaults
+func UpstreamConfigFromEnv() UpstreamConfig {
+	return UpstreamConfig{
+		PollInterval: config.DurationFromEnv("UPSTREAM_POLL_INTERVAL", DEFAULT_UPSTREAM_POLL_INTERVAL),
+		MaxHeightLag: int64(config.IntFromEnv("UPSTREAM_MAX_HEIGHT_LAG", DEFAULT_UPSTREAM_MAX_HEIGHT_LAG, 0)),
+		MaxLatency:   config.DurationFromEnv("UPSTREAM_MAX_LATENCY", DEFAULT_UPSTREAM_MAX_LATENCY),
+	}
+}
+
+// heightFunc parses the block height from the status response of an upstream
+type heightFunc = func(body []byte) (int64, error)
+
+// upstream is a single lcd or rpc endpoint along with its passively tracked health
+type upstream struct {
+	name    string
+	baseURL *url.URL
+	headers map[string]string
+
+	m         sync.Mutex
+	errorRate float64
+	latency   time.Duration
+	height    int64
+	healthy   bool
+
+	// circuit breaker state
+	failures  int
+	openUntil time.Time
+	trial     bool
+}
+
+func newUpstream(endpoint Endpoint) *upstream {
+	baseURL := *endpoint.URL
+	headers := map[string]string{}
+
+	if endpoint.APIKEY != "" {
+		isLiquify := strings.Contains(endpoint.URL.String(), "liquify")
+		if isLiquify {
+			baseURL.Path = path.Join(baseURL.Path, fmt.Sprintf("api=%s", endpoint.APIKEY))
+		}
+
+		isNownodes := strings.Contains(endpoint.URL.String(), "nownodes")
+		if isNownodes {
+			headers["Authorization"] = fmt.Sprintf("Basic %s", endpoint.APIKEY)
+		}
+	}
+
+	// match the base url of the resty client, which trims any trailing slash
+	baseURL.Path = strings.TrimRight(baseURL.Path, "/")
+
+	return &upstream{name: endpoint.Name, baseURL: &baseURL, headers: headers, healthy: true}
+}
+
+// observe the outcome of a request, returning true if the health of the upstream changed
+func (u *upstream) observe(failed bool, latency time.Duration, maxLatency time.Duration) bool {
+	u.m.Lock()
+	defer u.m.Unlock()
+
+	failure := 0.0
+	if failed {
+		failure = 1
+	}
+
+	u.errorRate = upstreamAlpha*failure + (1-upstreamAlpha)*u.errorRate
+
+	// latency of a failed request is not representative of the upstream
+	if !failed {
+		if u.latency == 0 {
+			u.latency = latency
+		} else {
+			u.latency = time.Duration(upstreamAlpha*float64(latency) + (1-upstreamAlpha)*float64(u.latency))
+		}
+	}
+
+	healthy := u.errorRate <= upstreamMaxErrorRate && u.latency <= maxLatency
+	changed := healthy != u.healthy
+	u.healthy = healthy
+
+	return changed
+}
+
+// record the outcome of a request for the circuit breaker, returning true if the circuit was opened
+func (u *upstream) record(failed bool, policy Policy) bool {
+	u.m.Lock()
+	defer u.m.Unlock()
+
+	u.trial = false
+
+	if !failed {
+		u.failures = 0
+		u.openUntil = time.Time{}
+		return false
+	}
+
+	u.failures++
+
+	// open the circuit once the threshold is reached, or again if the trial request failed
+	if u.failures >= policy.BreakerThreshold {
+		u.openUntil = time.Now().Add(policy.BreakerCooldown)
+		return u.failures == policy.BreakerThreshold
+	}
+
+	return false
+}
+
+// allow returns true if the circuit is closed, or if the cooldown has elapsed and no other trial request is in flight
+func (u *upstream) allow() bool {
+	u.m.Lock()
+	defer u.m.Unlock()
+
+	if u.failures < 1 || u.openUntil.IsZero() {
+		return true
+	}
+
+	if time.Now().Before(u.openUntil) || u.trial {
+		return false
+	}
+
+	u.trial = true
+
+	return true
+}
+
+// release a trial request without recording an outcome, allowing another trial request through the open circuit
+func (u *upstream) release() {
+	u.m.Lock()
+	defer u.m.Unlock()
+
+	u.trial = false
+}
+
+func (u *upstream) setHeight(height int64) {
+	u.m.Lock()
+	defer u.m.Unlock()
+
+	if height > u.height {
+		u.height = height
+	}
+}
+
+func (u *upstream) state() (healthy bool, errorRate float64, height int64) {
+	u.m.Lock()
+	defer u.m.Unlock()
+
+	return u.healthy, u.errorRate, u.height
+}
+
+// request returns a copy of the request made to the upstream instead of the primary upstream the resty client is configured with
+func (u *upstream) request(req *http.Request, primary *upstream) *http.Request {
+	r := req.Clone(req.Context())
+
+	r.URL.Scheme = u.baseURL.Scheme
+	r.URL.Host = u.baseURL.Host
+	r.URL.Path = u.baseURL.Path + strings.TrimPrefix(req.URL.Path, primary.baseURL.Path)
+	r.URL.RawPath = ""
+	r.Host = u.baseURL.Host
+
+	for k, v := range u.headers {
+		r.Header.Set(k, v)
+	}
+
+	return r
+}
+
+// upstreamPool routes requests to the most preferred healthy upstream, failing over to the next upstream in order of preference.
+// Idempotent requests are retried on the next upstream if the upstream is unavailable, all other requests are only sent once.
+type upstreamPool struct {
+	name       string
+	upstreams  []*upstream
+	transport  http.RoundTripper
+	conf       UpstreamConfig
+	policy     Policy
+	statusPath string
+	height     heightFunc
+	prometheus *metrics.Prometheus
+	done       chan struct{}
+	stopOnce   sync.Once
+}
+
+func newUpstreamPool(name string, endpoints []Endpoint, statusPath string, height heightFunc, conf UpstreamConfig, policy Policy, prometheus *metrics.Prometheus) *upstreamPool {
+	p := &upstreamPool{
+		name:       name,
+		transport:  tracing.Transport(name),
+		conf:       conf,
+		policy:     policy,
+		statusPath: statusPath,
+		height:     height,
+		prometheus: prometheus,
+		done:       make(chan struct{}),
+	}
+
+	for _, endpoint := range endpoints {
+		p.upstreams = append(p.upstreams, newUpstream(endpoint))
+	}
+
+	// height lag is only relevant when there is another upstream to fail over to
+	if len(p.upstreams) > 1 && statusPath != "" {
+		go p.poll()
+	}
+
+	return p
+}
+
+// Close stops polling the status of the upstreams
+func (p *upstreamPool) Close() {
+	p.stopOnce.Do(func() { close(p.done) })
+}
+
+// primary upstream the resty client is configured with
+func (p *upstreamPool) primary() *upstream {
+	return p.upstreams[0]
+}
+
+// RoundTrip sends the request to the most preferred healthy upstream with a closed circuit, failing fast if every circuit is open
+func (p *upstreamPool) RoundTrip(req *http.Request) (*http.Response, error) {
+	idempotent := req.Method == http.MethodGet || req.Method == http.MethodHead
+
+	var res *http.Response
+	var err error
+	var prev *upstream
+
+	for _, u := range p.candidates() {
+		if !u.allow() {
+			continue
+		}
+
+		if prev != nil {
+			if res != nil {
+				_, _ = io.Copy(io.Discard, res.Body)
+				res.Body.Close()
+			}
+
+			logger.Warnf("%s upstream unavailable: %s: failing over to: %s", p.name, prev.name, u.name)
+		}
+
+		res, err = p.attempt(u, req)
+
+		if !isUnavailable(res, err) || !idempotent || req.Context().Err() != nil {
+			return res, err
+		}
+
+		prev = u
+	}
+
+	if prev == nil {
+		return nil, circuitOpenError(p.name)
+	}
+
+	return res, err
+}
+
+// attempt the request to the upstream, bounded by the timeout of the policy
+func (p *upstreamPool) attempt(u *upstream, req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), p.policy.Timeout)
+
+	start := time.Now()
+	res, err := p.transport.RoundTrip(u.request(req.WithContext(ctx), p.primary()))
+	duration := time.Since(start)
+
+	status := "error"
+	if err != nil {
+		cancel()
+	} else {
+		status = strconv.Itoa(res.StatusCode)
+
+		// the response body is read after the round trip returns, so the timeout is only released once the body is closed
+		res.Body = &cancelReadCloser{ReadCloser: res.Body, cancel: cancel}
+
+		if height, err := strconv.ParseInt(res.Header.Get(blockHeightHeader), 10, 64); err == nil {
+			u.setHeight(height)
+		}
+	}
+
+	p.prometheus.Metrics.UpstreamRequestCounter.With(metrics.Labels{"client": p.name, "upstream": u.name, "status": status}).Inc()
+	p.prometheus.Metrics.UpstreamRequestDurationSeconds.With(metrics.Labels{"client": p.name, "upstream": u.name}).Observe(duration.Seconds())
+
+	// a request cancelled by the caller is not a failure of the upstream, but any trial request is released so the next request is allowed
+	if req.Context().Err() == nil {
+		p.observe(u, isUnavailable(res, err), duration)
+	} else {
+		u.release()
+	}
+
+	return res, err
+}
+
+type cancelReadCloser struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelReadCloser) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
+
+// candidates returns the healthy upstreams in order of preference, followed by any unhealthy upstreams in order of error rate
+func (p *upstreamPool) candidates() []*upstream {
+	if len(p.upstreams) == 1 {
+		return p.upstreams
+	}
+
+	maxHeight := p.maxHeight()
+
+	healthy := make([]*upstream, 0, len(p.upstreams))
+	unhealthy := make([]*upstream, 0, len(p.upstreams))
+	errorRates := make(map[*upstream]float64, len(p.upstreams))
+
+	for _, u := range p.upstreams {
+		ok, errorRate, height := u.state()
+		if ok && !p.isLagging(height, maxHeight) {
+			healthy = append(healthy, u)
+		} else {
+			unhealthy = append(unhealthy, u)
+			errorRates[u] = errorRate
+		}
+	}
+
+	// stable sort retains the order of preference of upstreams with equal error rates
+	sort.SliceStable(unhealthy, func(i, j int) bool { return errorRates[unhealthy[i]] < errorRates[unhealthy[j]] })
+
+	return append(healthy, unhealthy...)
+}
+
+func (p *upstreamPool) maxHeight() int64 {
+	var maxHeight int64
+	for _, u := range p.upstreams {
+		if _, _, height := u.state(); height > maxHeight {
+			maxHeight = height
+		}
+	}
+
+	return maxHeight
+}
+
+// isLagging returns true if the upstream height is too far behind the highest upstream, or has not been determined yet
+func (p *upstreamPool) isLagging(height int64, maxHeight int64) bool {
+	return maxHeight > 0 && maxHeight-height > p.conf.MaxHeightLag
+}
+
+func (p *upstreamPool) observe(u *upstream, failed bool, latency time.Duration) {
+	if u.record(failed, p.policy) {
+		logger.Warnf("%s upstream circuit open: %s (cooldown: %s)", p.name, u.name, p.policy.BreakerCooldown)
+	}
+
+	if !u.observe(failed, latency, p.conf.MaxLatency) {
+		return
+	}
+
+	if healthy, errorRate, _ := u.state(); healthy {
+		logger.Infof("%s upstream recovered: %s", p.name, u.name)
+	} else {
+		logger.Warnf("%s upstream unhealthy: %s (error rate: %.2f)", p.name, u.name, errorRate)
+	}
+}
+
+// poll the status of each upstream to track block height lag and to detect recovery of any unhealthy upstreams not receiving requests
+func (p *upstreamPool) poll() {
+	ticker := time.NewTicker(p.conf.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		var wg sync.WaitGroup
+		for _, u := range p.upstreams {
+			wg.Add(1)
+			go func(u *upstream) {
+				defer wg.Done()
+				p.pollStatus(u)
+			}(u)
+		}
+
+		wg.Wait()
+
+		maxHeight := p.maxHeight()
+		for _, u := range p.upstreams {
+			healthy, _, height := u.state()
+
+			lag := int64(0)
+			if height > 0 {
+				lag = maxHeight - height
+			}
+
+			labels := metrics.Labels{"client": p.name, "upstream": u.name}
+			p.prometheus.Metrics.UpstreamHeightLag.With(labels).Set(float64(lag))
+
+			if healthy && !p.isLagging(height, maxHeight) {
+				p.prometheus.Metrics.UpstreamHealthy.With(labels).Set(1)
+			} else {
+				p.prometheus.Metrics.UpstreamHealthy.With(labels).Set(0)
+			}
+		}
+
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *upstreamPool) pollStatus(u *upstream) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.conf.PollInterval)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.primary().baseURL.String()+p.statusPath, nil)
+	if err != nil {
+		logger.Errorf("failed to create %s status request: %v", p.name, err)
+		return
+	}
+
+	start := time.Now()
+	res, err := p.transport.RoundTrip(u.request(req, p.primary()))
+	duration := time.Since(start)
+
+	if isUnavailable(res, err) {
+		if res != nil {
+			res.Body.Close()
+		}
+
+		p.observe(u, true, duration)
+		return
+	}
+
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		p.observe(u, true, duration)
+		return
+	}
+
+	p.observe(u, false, duration)
+
+	height, err := p.height(body)
+	if err != nil {
+		logger.Errorf("failed to get %s upstream height: %s: %v", p.name, u.name, err)
+		return
+	}
+
+	u.setHeight(height)
+}
+
+// isUnavailable returns true if the upstream could not be reached or responded as unavailable.
+// Any other error response (ex. tx not found) is a valid response from an available upstream.
+func isUnavailable(res *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+
+	switch res.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// rpcHeight parses the latest block height from the rpc /status response
+func rpcHeight(body []byte) (int64, error) {
+	var res struct {
+		Result struct {
+			SyncInfo struct {
+				LatestBlockHeight string `json:"latest_block_height"`
+			} `json:"sync_info"`
+		} `json:"result"`
+	}
+
+	if err := json.Unmarshal(body, &res); err != nil {
+		return 0, errors.Wrap(err, "failed to unmarshal status")
+	}
+
+	height, err := strconv.ParseInt(res.Result.SyncInfo.LatestBlockHeight, 10, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid latest block height: %s", res.Result.SyncInfo.LatestBlockHeight)
+	}
+
+	return height, nil
+}
+
+// lcdHeight parses the latest block height from the lcd latest block response
+func lcdHeight(body []byte) (int64, error) {
+	var res struct {
+		Block struct {
+			Header struct {
+				Height string `json:"height"`
+			} `json:"header"`
+		} `json:"block"`
+	}
+
+	if err := json.Unmarshal(body, &res); err != nil {
+		return 0, errors.Wrap(err, "failed to unmarshal latest block")
+	}
+
+	height, err := strconv.ParseInt(res.Block.Header.Height, 10, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid latest block height: %s", res.Block.Header.Height)
+	}
+
+	return height, nil
+}
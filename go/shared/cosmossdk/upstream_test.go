@@ -0,0 +1,216 @@
+package cosmossdk
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shapeshift/unchained/shared/metrics"
+)
+
+func TestCancelledTrialReleasesCircuit(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusServiceUnavailable)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/hang" {
+			<-r.Context().Done()
+			return
+		}
+
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer server.Close()
+
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("failed to parse url: %+v", err)
+	}
+
+	policy := Policy{Timeout: time.Second, BreakerThreshold: 1, BreakerCooldown: 50 * time.Millisecond}
+	pool := newUpstreamPool("test", []Endpoint{{Name: "test", URL: u}}, "", nil, UpstreamConfigFromEnv(), policy, metrics.NewPrometheus("test"))
+
+	do := func(ctx context.Context, path string) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+path, nil)
+		if err != nil {
+			t.Fatalf("failed to create request: %+v", err)
+		}
+
+		res, err := pool.RoundTrip(req)
+		if err == nil {
+			res.Body.Close()
+		}
+
+		return res, err
+	}
+
+	// open the circuit
+	if _, err := do(context.Background(), "/"); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	if _, err := do(context.Background(), "/"); err == nil {
+		t.Fatal("expected circuit to be open")
+	}
+
+	time.Sleep(policy.BreakerCooldown)
+
+	// cancel the trial request allowed through after the cooldown
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := do(ctx, "/hang"); err == nil {
+		t.Fatal("expected trial request to be cancelled")
+	}
+
+	status.Store(http.StatusOK)
+
+	res, err := do(context.Background(), "/")
+	if err != nil {
+		t.Fatalf("expected another trial request to be allowed: %+v", err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status: %d", res.StatusCode)
+	}
+}
+
+// testUpstream is an rpc node with a configurable block height and status code
+type testUpstream struct {
+	*httptest.Server
+	height   atomic.Int64
+	polls    atomic.Int32
+	status   atomic.Int32
+	endpoint Endpoint
+}
+
+func newTestUpstream(t *testing.T, name string, height int64) *testUpstream {
+	t.Helper()
+
+	u := &testUpstream{}
+	u.height.Store(height)
+	u.status.Store(http.StatusOK)
+
+	u.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/status" {
+			u.polls.Add(1)
+		}
+
+		w.WriteHeader(int(u.status.Load()))
+		fmt.Fprintf(w, `{"result":{"sync_info":{"latest_block_height":"%d"}}}`, u.height.Load())
+	}))
+	t.Cleanup(u.Close)
+
+	baseURL, err := url.Parse(u.URL)
+	if err != nil {
+		t.Fatalf("failed to parse url: %+v", err)
+	}
+
+	u.endpoint = Endpoint{Name: name, URL: baseURL}
+
+	return u
+}
+
+func newTestPool(t *testing.T, upstreams ...*testUpstream) *upstreamPool {
+	t.Helper()
+
+	endpoints := []Endpoint{}
+	for _, u := range upstreams {
+		endpoints = append(endpoints, u.endpoint)
+	}
+
+	conf := UpstreamConfig{PollInterval: 10 * time.Millisecond, MaxHeightLag: 5, MaxLatency: time.Second}
+	policy := Policy{Timeout: time.Second, BreakerThreshold: 100, BreakerCooldown: time.Second}
+
+	pool := newUpstreamPool("test", endpoints, "/status", rpcHeight, conf, policy, metrics.NewPrometheus("test"))
+	t.Cleanup(pool.Close)
+
+	return pool
+}
+
+// waitForPreferred fails the test if the upstream is not the most preferred candidate before the timeout
+func waitForPreferred(t *testing.T, pool *upstreamPool, name string) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for pool.candidates()[0].name != name {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected upstream %s to be preferred, got %s", name, pool.candidates()[0].name)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestHeightLagExclusion(t *testing.T) {
+	primary := newTestUpstream(t, "primary", 100)
+	secondary := newTestUpstream(t, "secondary", 110)
+
+	pool := newTestPool(t, primary, secondary)
+
+	// primary is excluded while more than the max height lag behind
+	waitForPreferred(t, pool, "secondary")
+
+	req, err := http.NewRequest(http.MethodGet, primary.URL+"/block", nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %+v", err)
+	}
+
+	res, err := pool.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	res.Body.Close()
+
+	if host := res.Request.URL.Host; host != secondary.endpoint.URL.Host {
+		t.Errorf("expected request to be routed to secondary %s, got %s", secondary.endpoint.URL.Host, host)
+	}
+
+	// primary is preferred again once within the max height lag
+	primary.height.Store(108)
+
+	waitForPreferred(t, pool, "primary")
+}
+
+func TestUnhealthyUpstreamRecovers(t *testing.T) {
+	primary := newTestUpstream(t, "primary", 100)
+	secondary := newTestUpstream(t, "secondary", 100)
+
+	pool := newTestPool(t, primary, secondary)
+
+	waitForPreferred(t, pool, "primary")
+
+	// status polls mark the primary unhealthy without any requests being routed to it
+	primary.status.Store(http.StatusServiceUnavailable)
+
+	waitForPreferred(t, pool, "secondary")
+
+	primary.status.Store(http.StatusOK)
+
+	waitForPreferred(t, pool, "primary")
+}
+
+func TestCloseStopsPolling(t *testing.T) {
+	primary := newTestUpstream(t, "primary", 100)
+	secondary := newTestUpstream(t, "secondary", 100)
+
+	pool := newTestPool(t, primary, secondary)
+
+	waitForPreferred(t, pool, "primary")
+
+	pool.Close()
+
+	// allow any in flight poll to complete
+	time.Sleep(5 * pool.conf.PollInterval)
+	polls := primary.polls.Load()
+
+	time.Sleep(5 * pool.conf.PollInterval)
+
+	if n := primary.polls.Load(); n != polls {
+		t.Errorf("expected polling to stop after close, got %d more polls", n-polls)
+	}
+}
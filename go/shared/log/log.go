@@ -4,7 +4,10 @@
 package log
 
 import (
+	"context"
+
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Fields is an alias to logrus.Fields
@@ -37,3 +40,32 @@ func WithFields(fields Fields) Logger {
 func WithoutFields() Logger {
 	return logger
 }
+
+type requestIDKey struct{}
+
+// ContextWithRequestID returns a copy of the context annotated with the request id
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID returns the request id of the context, if any
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// WithContext adds the request id and trace id of the context as field annotations to correlate logs with a request and its trace
+func WithContext(ctx context.Context) Logger {
+	fields := Fields{}
+
+	if requestID := RequestID(ctx); requestID != "" {
+		fields["requestId"] = requestID
+	}
+
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		fields["traceId"] = sc.TraceID().String()
+		fields["spanId"] = sc.SpanID().String()
+	}
+
+	return logger.WithFields(fields)
+}
@@ -13,9 +13,24 @@ type Prometheus struct {
 }
 
 type Metrics struct {
-	HTTPRequestCounter         *prometheus.CounterVec
-	HTTPRequestDurationSeconds *prometheus.HistogramVec
-	WebsocketCount             prometheus.Gauge
+	HTTPRequestCounter             *prometheus.CounterVec
+	HTTPRequestDurationSeconds     *prometheus.HistogramVec
+	HTTPRateLimited                *prometheus.CounterVec
+	WebsocketCount                 prometheus.Gauge
+	WebsocketQueueDepth            prometheus.Gauge
+	WebsocketDroppedMessages       *prometheus.CounterVec
+	WebsocketSubscriptionCount     prometheus.Gauge
+	WebsocketAddressCount          prometheus.Gauge
+	WebsocketLimitExceeded         *prometheus.CounterVec
+	WebsocketFeedFirstBlocks       *prometheus.CounterVec
+	WebhookCount                   prometheus.Gauge
+	WebhookDeliveries              *prometheus.CounterVec
+	WebhookDeliveryDurationSeconds prometheus.Histogram
+	WebhookDeadLetterCount         prometheus.Gauge
+	UpstreamRequestCounter         *prometheus.CounterVec
+	UpstreamRequestDurationSeconds *prometheus.HistogramVec
+	UpstreamHealthy                *prometheus.GaugeVec
+	UpstreamHeightLag              *prometheus.GaugeVec
 }
 
 type Labels = prometheus.Labels
@@ -35,7 +50,7 @@ func NewPrometheus(coinstack string) *Prometheus {
 				Help:        "Count of http requests",
 				ConstLabels: prometheus.Labels{"coinstack": coinstack},
 			},
-			[]string{"method", "route", "statusCode"},
+			[]string{"method", "route", "statusCode", "key"},
 		),
 		HTTPRequestDurationSeconds: prometheus.NewHistogramVec(
 			prometheus.HistogramOpts{
@@ -44,13 +59,117 @@ func NewPrometheus(coinstack string) *Prometheus {
 				ConstLabels: prometheus.Labels{"coinstack": coinstack},
 				Buckets:     []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
 			},
-			[]string{"method", "route", "statusCode"},
+			[]string{"method", "route", "statusCode", "key"},
+		),
+		HTTPRateLimited: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        "unchained_http_rate_limited_count",
+				Help:        "Count of http requests rejected for exceeding the rate limit of an api key",
+				ConstLabels: prometheus.Labels{"coinstack": coinstack},
+			},
+			[]string{"key", "class"},
 		),
 		WebsocketCount: prometheus.NewGauge(prometheus.GaugeOpts{
 			Name:        "unchained_ws_client_count",
 			Help:        "Count of websocket client connections",
 			ConstLabels: prometheus.Labels{"coinstack": coinstack},
 		}),
+		WebsocketQueueDepth: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name:        "unchained_ws_queue_depth",
+			Help:        "Count of messages queued for delivery across all websocket client connections",
+			ConstLabels: prometheus.Labels{"coinstack": coinstack},
+		}),
+		WebsocketDroppedMessages: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        "unchained_ws_dropped_message_count",
+				Help:        "Count of messages dropped due to a full websocket client queue",
+				ConstLabels: prometheus.Labels{"coinstack": coinstack},
+			},
+			[]string{"policy"},
+		),
+		WebsocketSubscriptionCount: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name:        "unchained_ws_subscription_count",
+			Help:        "Count of subscriptions across all websocket client connections",
+			ConstLabels: prometheus.Labels{"coinstack": coinstack},
+		}),
+		WebsocketAddressCount: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name:        "unchained_ws_address_count",
+			Help:        "Count of subscribed addresses across all websocket client connections",
+			ConstLabels: prometheus.Labels{"coinstack": coinstack},
+		}),
+		WebsocketLimitExceeded: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        "unchained_ws_limit_exceeded_count",
+				Help:        "Count of websocket requests rejected for exceeding a limit",
+				ConstLabels: prometheus.Labels{"coinstack": coinstack},
+			},
+			[]string{"limit"},
+		),
+		WebsocketFeedFirstBlocks: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        "unchained_ws_feed_first_block_count",
+				Help:        "Count of new blocks by the upstream websocket feed that delivered the block first",
+				ConstLabels: prometheus.Labels{"coinstack": coinstack},
+			},
+			[]string{"feed"},
+		),
+		WebhookCount: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name:        "unchained_webhook_count",
+			Help:        "Count of registered webhooks",
+			ConstLabels: prometheus.Labels{"coinstack": coinstack},
+		}),
+		WebhookDeliveries: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        "unchained_webhook_delivery_count",
+				Help:        "Count of webhook delivery attempts by outcome",
+				ConstLabels: prometheus.Labels{"coinstack": coinstack},
+			},
+			[]string{"status"},
+		),
+		WebhookDeliveryDurationSeconds: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:        "unchained_webhook_delivery_duration_seconds",
+			Help:        "Duration of webhook delivery attempts in seconds",
+			ConstLabels: prometheus.Labels{"coinstack": coinstack},
+			Buckets:     []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+		}),
+		WebhookDeadLetterCount: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name:        "unchained_webhook_dead_letter_count",
+			Help:        "Count of webhook deliveries dead lettered after failing all attempts",
+			ConstLabels: prometheus.Labels{"coinstack": coinstack},
+		}),
+		UpstreamRequestCounter: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        "unchained_upstream_request_count",
+				Help:        "Count of requests by the upstream endpoint that served the request",
+				ConstLabels: prometheus.Labels{"coinstack": coinstack},
+			},
+			[]string{"client", "upstream", "status"},
+		),
+		UpstreamRequestDurationSeconds: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:        "unchained_upstream_request_duration_seconds",
+				Help:        "Duration of requests to upstream endpoints in seconds",
+				ConstLabels: prometheus.Labels{"coinstack": coinstack},
+				Buckets:     []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+			},
+			[]string{"client", "upstream"},
+		),
+		UpstreamHealthy: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name:        "unchained_upstream_healthy",
+				Help:        "Health of each upstream endpoint (1 if healthy, 0 if unhealthy)",
+				ConstLabels: prometheus.Labels{"coinstack": coinstack},
+			},
+			[]string{"client", "upstream"},
+		),
+		UpstreamHeightLag: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name:        "unchained_upstream_height_lag",
+				Help:        "Number of blocks each upstream endpoint is behind the highest upstream endpoint",
+				ConstLabels: prometheus.Labels{"coinstack": coinstack},
+			},
+			[]string{"client", "upstream"},
+		),
 	}
 
 	v := reflect.ValueOf(metrics)
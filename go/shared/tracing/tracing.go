@@ -0,0 +1,133 @@
+// Tracing of api requests and upstream calls exported to an OpenTelemetry collector.
+// Exporting is configured using the standard OTEL_EXPORTER_OTLP_* environment variables.
+
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/shapeshift/unchained/shared/log"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+)
+
+var logger = log.WithoutFields()
+
+const (
+	// min length of an upstream path segment replaced with a placeholder in span names (ex. addresses and hashes)
+	minParamLength = 32
+	// prefix of an upstream path segment containing an api key
+	apiKeyPrefix = "api="
+)
+
+// Start exporting traces for the coinstack if an otlp endpoint is configured (ex. OTEL_EXPORTER_OTLP_ENDPOINT=http://localhost:4318).
+// The returned shutdown function flushes any pending spans and must be called before exiting.
+func Start(coinstack string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+		logger.Info("OTEL_EXPORTER_OTLP_ENDPOINT not set: trace export disabled")
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(context.Background())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create otlp trace exporter")
+	}
+
+	// any OTEL_RESOURCE_ATTRIBUTES or OTEL_SERVICE_NAME provided take precedence
+	res, err := resource.New(context.Background(),
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(semconv.ServiceName(fmt.Sprintf("unchained-%s", coinstack))),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create trace resource")
+	}
+
+	// sampling is configured using OTEL_TRACES_SAMPLER and OTEL_TRACES_SAMPLER_ARG (default: parentbased_always_on)
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// Transport wraps the default transport to create a span for each upstream request made by the named client (ex. lcd or rpc).
+// Any api key in the request path is redacted from the span, which records the full request url.
+func Transport(name string) http.RoundTripper {
+	traced := otelhttp.NewTransport(restoreTransport{next: http.DefaultTransport}, otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+		return fmt.Sprintf("%s %s %s", name, r.Method, pathTemplate(r.URL.Path))
+	}))
+
+	return redactTransport{next: traced}
+}
+
+// context key of the original url of a request with a redacted url
+type urlKey struct{}
+
+// redactTransport replaces the request url with a redacted copy for the wrapped transport to trace
+type redactTransport struct {
+	next http.RoundTripper
+}
+
+func (t redactTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	req := r.Clone(context.WithValue(r.Context(), urlKey{}, r.URL))
+	req.URL = redactURL(r.URL)
+
+	return t.next.RoundTrip(req)
+}
+
+// restoreTransport restores the original request url redacted for tracing before the request is sent
+type restoreTransport struct {
+	next http.RoundTripper
+}
+
+func (t restoreTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if u, ok := r.Context().Value(urlKey{}).(*url.URL); ok {
+		r = r.Clone(r.Context())
+		r.URL = u
+	}
+
+	return t.next.RoundTrip(r)
+}
+
+// redactURL returns a copy of the url with any api key path segment (ex. liquify: /api=<key>) redacted
+func redactURL(u *url.URL) *url.URL {
+	redacted := *u
+	redacted.User = nil
+	redacted.RawPath = ""
+
+	segments := strings.Split(u.Path, "/")
+	for i, s := range segments {
+		if strings.HasPrefix(s, apiKeyPrefix) {
+			segments[i] = apiKeyPrefix + "REDACTED"
+		}
+	}
+	redacted.Path = strings.Join(segments, "/")
+
+	return &redacted
+}
+
+// pathTemplate replaces any path segments likely to be an address or hash with a placeholder to keep span names low cardinality
+func pathTemplate(path string) string {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		if len(s) >= minParamLength {
+			segments[i] = "{param}"
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
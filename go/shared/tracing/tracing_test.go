@@ -0,0 +1,56 @@
+package tracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTransportRedactsAPIKey(t *testing.T) {
+	const key = "secretkey"
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+	}))
+	t.Cleanup(server.Close)
+
+	client := &http.Client{Transport: Transport("lcd")}
+
+	res, err := client.Get(server.URL + "/api=" + key + "/status?height=1")
+	if err != nil {
+		t.Fatalf("failed to make request: %+v", err)
+	}
+	res.Body.Close()
+
+	if path != "/api="+key+"/status" {
+		t.Fatalf("expected api key to be sent upstream, got path: %s", path)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+
+	if strings.Contains(spans[0].Name(), key) {
+		t.Errorf("expected api key to be redacted from span name: %s", spans[0].Name())
+	}
+
+	for _, attr := range spans[0].Attributes() {
+		if strings.Contains(attr.Value.Emit(), key) {
+			t.Errorf("expected api key to be redacted from span attribute: %s=%s", attr.Key, attr.Value.Emit())
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package webhook
+
+import (
+	"os"
+	"time"
+
+	"github.com/shapeshift/unchained/shared/config"
+)
+
+const (
+	DEFAULT_WORKERS          = 4
+	DEFAULT_QUEUE_SIZE       = 1024
+	DEFAULT_TIMEOUT          = 10 * time.Second
+	DEFAULT_MAX_ATTEMPTS     = 8
+	DEFAULT_RETRY_BASE_DELAY = time.Second
+	DEFAULT_RETRY_MAX_DELAY  = 10 * time.Minute
+	DEFAULT_MAX_ADDRESSES    = 1000
+	DEFAULT_MAX_DEAD_LETTERS = 100
+)
+
+// Config for webhook delivery
+type Config struct {
+	// number of concurrent delivery workers
+	Workers int
+	// max number of deliveries queued for the workers
+	QueueSize int
+	// timeout of each delivery attempt
+	Timeout time.Duration
+	// max number of delivery attempts before a delivery is dead lettered
+	MaxAttempts int
+	// delay before the first retry, doubled for each subsequent retry
+	RetryBaseDelay time.Duration
+	// max delay between retries
+	RetryMaxDelay time.Duration
+	// max number of addresses per webhook
+	MaxAddresses int
+	// max number of dead lettered deliveries kept per webhook
+	MaxDeadLetters int
+	// path of the file webhooks and undelivered deliveries are persisted to, kept in memory only if empty
+	StorePath string
+	// allow webhook urls resolving to loopback, private or link local addresses
+	AllowPrivate bool
+	// the api is run as multiple replicas sharing a network broker, which disables webhooks as registrations are local to each replica
+	Replicated bool
+}
+
+// ConfigFromEnv loads any optional webhook config from the environment, falling back to defaults
+func ConfigFromEnv() Config {
+	conf := Config{
+		Workers:        config.IntFromEnv("WEBHOOK_WORKERS", DEFAULT_WORKERS, 1),
+		QueueSize:      config.IntFromEnv("WEBHOOK_QUEUE_SIZE", DEFAULT_QUEUE_SIZE, 1),
+		Timeout:        config.DurationFromEnv("WEBHOOK_TIMEOUT", DEFAULT_TIMEOUT),
+		MaxAttempts:    config.IntFromEnv("WEBHOOK_MAX_ATTEMPTS", DEFAULT_MAX_ATTEMPTS, 1),
+		RetryBaseDelay: config.DurationFromEnv("WEBHOOK_RETRY_BASE_DELAY", DEFAULT_RETRY_BASE_DELAY),
+		RetryMaxDelay:  config.DurationFromEnv("WEBHOOK_RETRY_MAX_DELAY", DEFAULT_RETRY_MAX_DELAY),
+		MaxAddresses:   config.IntFromEnv("WEBHOOK_MAX_ADDRESSES", DEFAULT_MAX_ADDRESSES, 1),
+		MaxDeadLetters: config.IntFromEnv("WEBHOOK_MAX_DEAD_LETTERS", DEFAULT_MAX_DEAD_LETTERS, 1),
+		StorePath:      os.Getenv("WEBHOOK_STORE_PATH"),
+		AllowPrivate:   config.BoolFromEnv("WEBHOOK_ALLOW_PRIVATE", false),
+		Replicated:     os.Getenv("BROKER_URL") != "",
+	}
+
+	return conf
+}
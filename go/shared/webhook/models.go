@@ -0,0 +1,91 @@
+package webhook
+
+import "encoding/json"
+
+// Contains info about a registered webhook
+// swagger:model Webhook
+type Webhook struct {
+	// required: true
+	ID string `json:"id"`
+	// required: true
+	// example: https://example.com/webhook
+	URL string `json:"url"`
+	// required: true
+	Addresses []string `json:"addresses"`
+	// Secret used to sign deliveries (only returned on registration)
+	Secret string `json:"secret,omitempty"`
+	// required: true
+	// example: 1643052655
+	CreatedAt int `json:"createdAt"`
+}
+
+// Contains info about a webhook delivery
+// swagger:model WebhookDelivery
+type Delivery struct {
+	// required: true
+	ID string `json:"id"`
+	// required: true
+	WebhookID string `json:"webhookId"`
+	// required: true
+	Payload json.RawMessage `json:"payload"`
+	// required: true
+	Attempts  int    `json:"attempts"`
+	LastError string `json:"lastError,omitempty"`
+	// required: true
+	// example: 1643052655
+	CreatedAt     int `json:"createdAt"`
+	NextAttemptAt int `json:"nextAttemptAt,omitempty"`
+}
+
+// Contains the dead lettered deliveries of a webhook, oldest first
+// swagger:model WebhookDeliveries
+type Deliveries []Delivery
+
+// Contains the body posted to a webhook url.
+// The id is the same for every attempt of a delivery so it can be used to ignore duplicates.
+// swagger:model WebhookPayload
+type Payload struct {
+	// required: true
+	ID string `json:"id"`
+	// required: true
+	WebhookID string `json:"webhookId"`
+	// required: true
+	Addresses []string `json:"addresses"`
+	// required: true
+	Data json.RawMessage `json:"data"`
+}
+
+type RegisterBody struct {
+	// Url to post deliveries to
+	// required: true
+	// example: https://example.com/webhook
+	URL string `json:"url"`
+	// Addresses to deliver transactions for
+	// required: true
+	Addresses []string `json:"addresses"`
+}
+
+// swagger:parameters RegisterWebhook
+type RegisterParam struct {
+	// in:body
+	Body struct {
+		RegisterBody
+	}
+}
+
+// swagger:parameters GetWebhook DeleteWebhook WebhookDeadLetters
+type IDParam struct {
+	// Webhook id
+	// in: path
+	// required: true
+	ID string `json:"id"`
+}
+
+// swagger:parameters RedeliverWebhook
+type RedeliverParam struct {
+	IDParam
+	// Dead lettered delivery id
+	// in: path
+	// required: true
+	DeliveryID string `json:"deliveryId"`
+}
@@ -0,0 +1,96 @@
+package webhook
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// snapshot of all state required to resume delivery after a restart
+type snapshot struct {
+	Webhooks    []*Webhook  `json:"webhooks"`
+	Pending     []*Delivery `json:"pending"`
+	DeadLetters []*Delivery `json:"deadLetters"`
+}
+
+// fileStore persists snapshots as json to a single file
+type fileStore struct {
+	lockFile *os.File
+	path     string
+}
+
+// lock the store for exclusive use by this process, failing if it is already locked by another replica sharing the store
+func (f *fileStore) lock() error {
+	file, err := os.OpenFile(f.path+".lock", os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open webhook store lock: %s", f.path)
+	}
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		file.Close()
+		return errors.Wrapf(err, "webhook store locked by another replica: %s", f.path)
+	}
+
+	f.lockFile = file
+
+	return nil
+}
+
+// unlock the store, closing the lock file releases the lock
+func (f *fileStore) unlock() {
+	if f.lockFile == nil {
+		return
+	}
+
+	f.lockFile.Close()
+	f.lockFile = nil
+}
+
+func (f *fileStore) load() (*snapshot, error) {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &snapshot{}, nil
+		}
+		return nil, errors.Wrapf(err, "failed to read webhook store: %s", f.path)
+	}
+
+	s := &snapshot{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal webhook store: %s", f.path)
+	}
+
+	return s, nil
+}
+
+// save the snapshot by writing to a temporary file first so a partial write never replaces the previous snapshot
+func (f *fileStore) save(s *snapshot) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal webhook store")
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*.tmp")
+	if err != nil {
+		return errors.Wrapf(err, "failed to create temporary webhook store: %s", f.path)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return errors.Wrapf(err, "failed to write temporary webhook store: %s", tmp.Name())
+	}
+
+	if err := tmp.Close(); err != nil {
+		return errors.Wrapf(err, "failed to close temporary webhook store: %s", tmp.Name())
+	}
+
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		return errors.Wrapf(err, "failed to replace webhook store: %s", f.path)
+	}
+
+	return nil
+}
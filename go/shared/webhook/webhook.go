@@ -0,0 +1,661 @@
+// Package webhook delivers signed http callbacks for activity on registered addresses.
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/shapeshift/unchained/shared/api"
+	"github.com/shapeshift/unchained/shared/log"
+	"github.com/shapeshift/unchained/shared/metrics"
+	"github.com/shapeshift/unchained/shared/websocket"
+)
+
+var logger = log.WithoutFields()
+
+const (
+	subscriptionID = "webhook"
+	flushInterval  = time.Second
+)
+
+var (
+	// ErrInvalidWebhook is returned when a webhook registration is rejected
+	ErrInvalidWebhook = errors.New("invalid webhook")
+	// ErrNotFound is returned when a webhook or delivery does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrQueueFull is returned when a delivery can not be queued until the workers catch up
+	ErrQueueFull = api.NewUnavailableError("delivery queue full")
+	// ErrDisabled is returned by all operations when webhooks are disabled as the api is run as multiple replicas
+	ErrDisabled = api.NewUnavailableError("webhooks are not available when running multiple replicas")
+)
+
+// Sign returns the hex encoded HMAC-SHA256 of the timestamp and body, as sent in the X-Webhook-Signature header.
+// Receivers should compute the same signature using the webhook secret and compare it against the header.
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify returns true if the X-Webhook-Signature header value is the signature of the timestamp and body using the webhook secret
+func Verify(secret string, timestamp string, body []byte, signature string) bool {
+	expected := fmt.Sprintf("sha256=%s", Sign(secret, timestamp, body))
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// Service registers webhooks with the websocket registry and delivers all messages published for their addresses.
+// Failed deliveries are retried with exponential backoff and dead lettered once all attempts are exhausted.
+//
+// Registrations and deliveries are local to the process, so only a single replica may serve webhooks:
+// the service is disabled if the api is run as multiple replicas sharing a network broker,
+// and the store is locked so a second replica sharing the same store fails to start.
+type Service struct {
+	client         *http.Client
+	conf           Config
+	deadLetters    map[string][]*Delivery
+	dirty          bool
+	disabled       bool
+	done           chan struct{}
+	isValidAddress websocket.AddressValidatorFunc
+	m              sync.Mutex
+	pending        map[string]*Delivery
+	prometheus     *metrics.Prometheus
+	queue          chan *Delivery
+	registry       websocket.Registrar
+	restored       []*Delivery
+	stopOnce       sync.Once
+	store          *fileStore
+	webhooks       map[string]*Webhook
+	wg             sync.WaitGroup
+}
+
+func NewService(registry websocket.Registrar, isValidAddress websocket.AddressValidatorFunc, prometheus *metrics.Prometheus, conf Config) (*Service, error) {
+	s := &Service{
+		client:         newHTTPClient(conf),
+		conf:           conf,
+		deadLetters:    make(map[string][]*Delivery),
+		done:           make(chan struct{}),
+		isValidAddress: isValidAddress,
+		pending:        make(map[string]*Delivery),
+		prometheus:     prometheus,
+		queue:          make(chan *Delivery, conf.QueueSize),
+		registry:       registry,
+		webhooks:       make(map[string]*Webhook),
+	}
+
+	if conf.Replicated {
+		logger.Warn("webhooks disabled: not supported when running multiple replicas")
+		s.disabled = true
+		return s, nil
+	}
+
+	if conf.StorePath != "" {
+		s.store = &fileStore{path: conf.StorePath}
+
+		if err := s.store.lock(); err != nil {
+			return nil, err
+		}
+
+		if err := s.restore(); err != nil {
+			s.store.unlock()
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// restore webhooks and any undelivered deliveries from the store
+func (s *Service) restore() error {
+	snapshot, err := s.store.load()
+	if err != nil {
+		return err
+	}
+
+	for _, w := range snapshot.Webhooks {
+		s.webhooks[w.ID] = w
+		s.registry.Subscribe(w.ID, subscriptionID, w.Addresses, &subscriber{service: s, webhookID: w.ID}, websocket.SubscribeOptions{Dedupe: true})
+	}
+
+	for _, d := range snapshot.Pending {
+		s.pending[d.ID] = d
+		s.restored = append(s.restored, d)
+	}
+
+	for _, d := range snapshot.DeadLetters {
+		s.deadLetters[d.WebhookID] = append(s.deadLetters[d.WebhookID], d)
+	}
+
+	s.prometheus.Metrics.WebhookCount.Set(float64(len(s.webhooks)))
+	s.prometheus.Metrics.WebhookDeadLetterCount.Set(float64(len(snapshot.DeadLetters)))
+
+	logger.Infof("restored %d webhooks with %d pending deliveries", len(snapshot.Webhooks), len(snapshot.Pending))
+
+	return nil
+}
+
+// Start delivering webhooks in the background
+func (s *Service) Start() {
+	if s.disabled {
+		return
+	}
+
+	for i := 0; i < s.conf.Workers; i++ {
+		s.wg.Add(1)
+		go s.work()
+	}
+
+	if s.store != nil {
+		s.wg.Add(1)
+		go s.flushLoop()
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	for _, d := range s.restored {
+		s.schedule(d, time.Until(time.Unix(int64(d.NextAttemptAt), 0)))
+	}
+
+	s.restored = nil
+}
+
+// Stop delivering webhooks, waiting for any in flight deliveries to complete before persisting all undelivered deliveries
+func (s *Service) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.done)
+		s.wg.Wait()
+		s.flush()
+
+		if s.store != nil {
+			s.store.unlock()
+		}
+	})
+}
+
+// Register a webhook url to receive a delivery for every transaction involving any of the addresses provided
+func (s *Service) Register(rawURL string, addrs []string) (*Webhook, error) {
+	if s.disabled {
+		return nil, ErrDisabled
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errors.Wrapf(ErrInvalidWebhook, "invalid url: %s", rawURL)
+	}
+
+	if len(addrs) == 0 {
+		return nil, errors.Wrap(ErrInvalidWebhook, "addresses required")
+	}
+
+	if len(addrs) > s.conf.MaxAddresses {
+		return nil, errors.Wrapf(ErrInvalidWebhook, "too many addresses: max %d", s.conf.MaxAddresses)
+	}
+
+	seen := make(map[string]struct{}, len(addrs))
+	unique := []string{}
+	for _, addr := range addrs {
+		if s.isValidAddress != nil && !s.isValidAddress(addr) {
+			return nil, errors.Wrapf(ErrInvalidWebhook, "invalid address: %s", addr)
+		}
+
+		if _, ok := seen[addr]; ok {
+			continue
+		}
+
+		seen[addr] = struct{}{}
+		unique = append(unique, addr)
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, errors.Wrap(err, "failed to generate webhook secret")
+	}
+
+	w := &Webhook{
+		ID:        uuid.NewString(),
+		URL:       u.String(),
+		Addresses: unique,
+		Secret:    hex.EncodeToString(secret),
+		CreatedAt: int(time.Now().Unix()),
+	}
+
+	s.m.Lock()
+	s.webhooks[w.ID] = w
+	s.dirty = true
+	s.prometheus.Metrics.WebhookCount.Set(float64(len(s.webhooks)))
+	s.m.Unlock()
+
+	s.registry.Subscribe(w.ID, subscriptionID, w.Addresses, &subscriber{service: s, webhookID: w.ID}, websocket.SubscribeOptions{Dedupe: true})
+
+	logger.Infof("registered webhook: %s, addresses: %d", w.ID, len(w.Addresses))
+
+	registered := *w
+	return &registered, nil
+}
+
+// Get a registered webhook without its secret
+func (s *Service) Get(id string) (*Webhook, error) {
+	if s.disabled {
+		return nil, ErrDisabled
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	w, ok := s.webhooks[id]
+	if !ok {
+		return nil, errors.Wrapf(ErrNotFound, "webhook: %s", id)
+	}
+
+	webhook := *w
+	webhook.Secret = ""
+
+	return &webhook, nil
+}
+
+// Delete a registered webhook along with any pending and dead lettered deliveries
+func (s *Service) Delete(id string) error {
+	if s.disabled {
+		return ErrDisabled
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if _, ok := s.webhooks[id]; !ok {
+		return errors.Wrapf(ErrNotFound, "webhook: %s", id)
+	}
+
+	s.registry.Unsubscribe(id, subscriptionID, nil, nil)
+
+	delete(s.webhooks, id)
+
+	// any pending deliveries still queued are discarded by the workers
+	for deliveryID, d := range s.pending {
+		if d.WebhookID == id {
+			delete(s.pending, deliveryID)
+		}
+	}
+
+	s.prometheus.Metrics.WebhookDeadLetterCount.Sub(float64(len(s.deadLetters[id])))
+	delete(s.deadLetters, id)
+
+	s.dirty = true
+	s.prometheus.Metrics.WebhookCount.Set(float64(len(s.webhooks)))
+
+	logger.Infof("deleted webhook: %s", id)
+
+	return nil
+}
+
+// DeadLetters returns the deliveries for a webhook that failed all attempts, oldest first
+func (s *Service) DeadLetters(id string) (Deliveries, error) {
+	if s.disabled {
+		return nil, ErrDisabled
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if _, ok := s.webhooks[id]; !ok {
+		return nil, errors.Wrapf(ErrNotFound, "webhook: %s", id)
+	}
+
+	deliveries := make(Deliveries, 0, len(s.deadLetters[id]))
+	for _, d := range s.deadLetters[id] {
+		deliveries = append(deliveries, *d)
+	}
+
+	return deliveries, nil
+}
+
+// Redeliver a dead lettered delivery, resetting its attempts
+func (s *Service) Redeliver(id string, deliveryID string) error {
+	if s.disabled {
+		return ErrDisabled
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if _, ok := s.webhooks[id]; !ok {
+		return errors.Wrapf(ErrNotFound, "webhook: %s", id)
+	}
+
+	for i, d := range s.deadLetters[id] {
+		if d.ID != deliveryID {
+			continue
+		}
+
+		select {
+		case s.queue <- d:
+		default:
+			return ErrQueueFull
+		}
+
+		s.deadLetters[id] = append(s.deadLetters[id][:i], s.deadLetters[id][i+1:]...)
+		s.prometheus.Metrics.WebhookDeadLetterCount.Dec()
+
+		d.Attempts = 0
+		d.LastError = ""
+		d.NextAttemptAt = 0
+		s.pending[d.ID] = d
+		s.dirty = true
+
+		return nil
+	}
+
+	return errors.Wrapf(ErrNotFound, "delivery: %s", deliveryID)
+}
+
+// subscriber receives the messages published for the addresses of a webhook
+type subscriber struct {
+	service   *Service
+	webhookID string
+}
+
+func (sub *subscriber) Send(msg *websocket.Message) {
+	data, err := msg.JSON()
+	if err != nil {
+		logger.Errorf("failed to encode webhook message: %+v", err)
+		return
+	}
+
+	sub.service.handleMessage(sub.webhookID, data)
+}
+
+func (s *Service) handleMessage(webhookID string, msg []byte) {
+	res := struct {
+		Addresses []string        `json:"addresses"`
+		Data      json.RawMessage `json:"data"`
+	}{}
+
+	if err := json.Unmarshal(msg, &res); err != nil {
+		logger.Errorf("failed to unmarshal webhook message: %v", err)
+		return
+	}
+
+	payload := Payload{
+		ID:        uuid.NewString(),
+		WebhookID: webhookID,
+		Addresses: res.Addresses,
+		Data:      res.Data,
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		logger.Errorf("failed to marshal webhook payload: %v", err)
+		return
+	}
+
+	d := &Delivery{
+		ID:        payload.ID,
+		WebhookID: webhookID,
+		Payload:   data,
+		CreatedAt: int(time.Now().Unix()),
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	// never block the publisher
+	select {
+	case s.queue <- d:
+		s.pending[d.ID] = d
+		s.dirty = true
+	default:
+		d.LastError = ErrQueueFull.Error()
+		s.deadLetter(d)
+		s.prometheus.Metrics.WebhookDeliveries.With(metrics.Labels{"status": "dropped"}).Inc()
+	}
+}
+
+func (s *Service) work() {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case d := <-s.queue:
+			s.deliver(d)
+		}
+	}
+}
+
+func (s *Service) deliver(d *Delivery) {
+	s.m.Lock()
+	w, ok := s.webhooks[d.WebhookID]
+	if !ok {
+		s.m.Unlock()
+		return
+	}
+	webhook := *w
+	s.m.Unlock()
+
+	start := time.Now()
+	err := s.post(&webhook, d)
+	s.prometheus.Metrics.WebhookDeliveryDurationSeconds.Observe(time.Since(start).Seconds())
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	// webhook deleted while delivering
+	if _, ok := s.pending[d.ID]; !ok {
+		return
+	}
+
+	d.Attempts++
+	s.dirty = true
+
+	if err == nil {
+		delete(s.pending, d.ID)
+		s.prometheus.Metrics.WebhookDeliveries.With(metrics.Labels{"status": "success"}).Inc()
+		return
+	}
+
+	d.LastError = err.Error()
+
+	if d.Attempts >= s.conf.MaxAttempts {
+		logger.Warnf("dead lettering delivery: %s for webhook: %s after %d attempts: %v", d.ID, d.WebhookID, d.Attempts, err)
+		delete(s.pending, d.ID)
+		s.deadLetter(d)
+		s.prometheus.Metrics.WebhookDeliveries.With(metrics.Labels{"status": "dead_letter"}).Inc()
+		return
+	}
+
+	delay := s.backoff(d.Attempts)
+	d.NextAttemptAt = int(time.Now().Add(delay).Unix())
+
+	logger.Debugf("retrying delivery: %s for webhook: %s in %s: %v", d.ID, d.WebhookID, delay, err)
+
+	s.schedule(d, delay)
+	s.prometheus.Metrics.WebhookDeliveries.With(metrics.Labels{"status": "retry"}).Inc()
+}
+
+// post the delivery payload to the webhook url, signed with the webhook secret
+func (s *Service) post(w *Webhook, d *Delivery) error {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return errors.Wrap(err, "failed to create request")
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "unchained-webhook")
+	req.Header.Set("X-Webhook-Id", w.ID)
+	req.Header.Set("X-Webhook-Delivery", d.ID)
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", fmt.Sprintf("sha256=%s", Sign(w.Secret, timestamp, d.Payload)))
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to post")
+	}
+	defer res.Body.Close()
+
+	// drain body to allow connection reuse
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 4096))
+
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		return errors.Errorf("unexpected status code: %d", res.StatusCode)
+	}
+
+	return nil
+}
+
+// backoff returns the delay before the next attempt, doubling for each attempt up to the max delay
+func (s *Service) backoff(attempts int) time.Duration {
+	delay := s.conf.RetryBaseDelay
+	for i := 1; i < attempts && delay < s.conf.RetryMaxDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > s.conf.RetryMaxDelay {
+		delay = s.conf.RetryMaxDelay
+	}
+
+	return delay
+}
+
+// schedule a delivery to be queued after the delay
+func (s *Service) schedule(d *Delivery, delay time.Duration) {
+	time.AfterFunc(delay, func() {
+		select {
+		case s.queue <- d:
+		case <-s.done:
+		}
+	})
+}
+
+// deadLetter expects the caller to hold the lock
+func (s *Service) deadLetter(d *Delivery) {
+	deadLetters := append(s.deadLetters[d.WebhookID], d)
+	s.prometheus.Metrics.WebhookDeadLetterCount.Inc()
+
+	// discard the oldest dead letters
+	if len(deadLetters) > s.conf.MaxDeadLetters {
+		discard := len(deadLetters) - s.conf.MaxDeadLetters
+		deadLetters = deadLetters[discard:]
+		s.prometheus.Metrics.WebhookDeadLetterCount.Sub(float64(discard))
+	}
+
+	s.deadLetters[d.WebhookID] = deadLetters
+	s.dirty = true
+}
+
+func (s *Service) flushLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.flush()
+		}
+	}
+}
+
+// flush any changes to the store
+func (s *Service) flush() {
+	if s.store == nil {
+		return
+	}
+
+	s.m.Lock()
+	if !s.dirty {
+		s.m.Unlock()
+		return
+	}
+
+	// copy state so no lock is held while writing
+	snapshot := &snapshot{}
+	for _, w := range s.webhooks {
+		webhook := *w
+		snapshot.Webhooks = append(snapshot.Webhooks, &webhook)
+	}
+	for _, d := range s.pending {
+		delivery := *d
+		snapshot.Pending = append(snapshot.Pending, &delivery)
+	}
+	for _, deadLetters := range s.deadLetters {
+		for _, d := range deadLetters {
+			delivery := *d
+			snapshot.DeadLetters = append(snapshot.DeadLetters, &delivery)
+		}
+	}
+
+	s.dirty = false
+	s.m.Unlock()
+
+	if err := s.store.save(snapshot); err != nil {
+		logger.Errorf("failed to save webhook store: %+v", err)
+
+		s.m.Lock()
+		s.dirty = true
+		s.m.Unlock()
+	}
+}
+
+// newHTTPClient creates a client that does not follow redirects and, unless allowed, refuses to connect to non public addresses
+func newHTTPClient(conf Config) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: conf.Timeout,
+		Control: func(network string, address string, c syscall.RawConn) error {
+			if conf.AllowPrivate {
+				return nil
+			}
+
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return errors.Wrapf(err, "invalid address: %s", address)
+			}
+
+			if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+				return errors.Errorf("address not allowed: %s", host)
+			}
+
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   conf.Timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func isPublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified())
+}
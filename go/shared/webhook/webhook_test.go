@@ -0,0 +1,463 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shapeshift/unchained/shared/api"
+	"github.com/shapeshift/unchained/shared/metrics"
+	"github.com/shapeshift/unchained/shared/websocket"
+)
+
+func testConfig() Config {
+	return Config{
+		Workers:        1,
+		QueueSize:      16,
+		Timeout:        time.Second,
+		MaxAttempts:    3,
+		RetryBaseDelay: time.Millisecond,
+		RetryMaxDelay:  5 * time.Millisecond,
+		MaxAddresses:   10,
+		MaxDeadLetters: 10,
+		// httptest servers listen on loopback
+		AllowPrivate: true,
+	}
+}
+
+func newTestService(t *testing.T, registry websocket.Registrar, conf Config) *Service {
+	t.Helper()
+
+	s, err := NewService(registry, nil, metrics.NewPrometheus("test"), conf)
+	if err != nil {
+		t.Fatalf("failed to create service: %+v", err)
+	}
+
+	return s
+}
+
+// eventually fails the test if the condition is not met before the timeout
+func eventually(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before timeout")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSign(t *testing.T) {
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		body      string
+		expected  string
+	}{
+		{
+			name:      "body",
+			secret:    "secret",
+			timestamp: "1700000000",
+			body:      `{"id":"1"}`,
+			expected:  "086f6aff7bd084c98679825129c5a64dbad88c760016d6d2c0fb123f27951d54",
+		},
+		{
+			name:      "empty body",
+			secret:    "secret",
+			timestamp: "1700000000",
+			body:      "",
+			expected:  "4bc5f74d868b97888288889c5d9d65df02526f94c1592a79fdf4fe8b26e311e5",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if signature := Sign(tt.secret, tt.timestamp, []byte(tt.body)); signature != tt.expected {
+				t.Errorf("expected signature %s, got %s", tt.expected, signature)
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	signature := "sha256=" + Sign("secret", "1700000000", []byte(`{"id":"1"}`))
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		body      string
+		signature string
+		expected  bool
+	}{
+		{name: "valid", secret: "secret", timestamp: "1700000000", body: `{"id":"1"}`, signature: signature, expected: true},
+		{name: "wrong secret", secret: "other", timestamp: "1700000000", body: `{"id":"1"}`, signature: signature, expected: false},
+		{name: "wrong timestamp", secret: "secret", timestamp: "1700000001", body: `{"id":"1"}`, signature: signature, expected: false},
+		{name: "tampered body", secret: "secret", timestamp: "1700000000", body: `{"id":"2"}`, signature: signature, expected: false},
+		{name: "missing prefix", secret: "secret", timestamp: "1700000000", body: `{"id":"1"}`, signature: strings.TrimPrefix(signature, "sha256="), expected: false},
+		{name: "empty signature", secret: "secret", timestamp: "1700000000", body: `{"id":"1"}`, signature: "", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if valid := Verify(tt.secret, tt.timestamp, []byte(tt.body), tt.signature); valid != tt.expected {
+				t.Errorf("expected %t, got %t", tt.expected, valid)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	s := &Service{conf: Config{RetryBaseDelay: time.Second, RetryMaxDelay: 10 * time.Second}}
+
+	tests := []struct {
+		attempts int
+		expected time.Duration
+	}{
+		{attempts: 1, expected: time.Second},
+		{attempts: 2, expected: 2 * time.Second},
+		{attempts: 3, expected: 4 * time.Second},
+		{attempts: 4, expected: 8 * time.Second},
+		{attempts: 5, expected: 10 * time.Second},
+		{attempts: 50, expected: 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if delay := s.backoff(tt.attempts); delay != tt.expected {
+			t.Errorf("attempts %d: expected delay %s, got %s", tt.attempts, tt.expected, delay)
+		}
+	}
+}
+
+func TestDeliverSignedPayload(t *testing.T) {
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+
+	receivedChan := make(chan received, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		receivedChan <- received{header: r.Header, body: body}
+	}))
+	defer server.Close()
+
+	registry := websocket.NewRegistry()
+	s := newTestService(t, registry, testConfig())
+	s.Start()
+	defer s.Stop()
+
+	wh, err := s.Register(server.URL, []string{"addr1", "addr1", "addr2"})
+	if err != nil {
+		t.Fatalf("failed to register webhook: %+v", err)
+	}
+
+	if !reflect.DeepEqual(wh.Addresses, []string{"addr1", "addr2"}) {
+		t.Errorf("expected duplicate addresses to be removed, got %v", wh.Addresses)
+	}
+
+	registry.Publish([]string{"addr1"}, map[string]string{"txid": "tx1"})
+
+	var r received
+	select {
+	case r = <-receivedChan:
+	case <-time.After(5 * time.Second):
+		t.Fatal("delivery not received")
+	}
+
+	if id := r.header.Get("X-Webhook-Id"); id != wh.ID {
+		t.Errorf("expected webhook id %s, got %s", wh.ID, id)
+	}
+
+	if !Verify(wh.Secret, r.header.Get("X-Webhook-Timestamp"), r.body, r.header.Get("X-Webhook-Signature")) {
+		t.Errorf("invalid signature: %s", r.header.Get("X-Webhook-Signature"))
+	}
+
+	payload := Payload{}
+	if err := json.Unmarshal(r.body, &payload); err != nil {
+		t.Fatalf("failed to unmarshal payload: %+v", err)
+	}
+
+	if payload.ID != r.header.Get("X-Webhook-Delivery") || payload.WebhookID != wh.ID {
+		t.Errorf("unexpected payload ids: %+v", payload)
+	}
+
+	if !reflect.DeepEqual(payload.Addresses, []string{"addr1"}) || string(payload.Data) != `{"txid":"tx1"}` {
+		t.Errorf("unexpected payload: %s", r.body)
+	}
+}
+
+func TestDeliverRetriesIntoDeadLetters(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	registry := websocket.NewRegistry()
+	conf := testConfig()
+	s := newTestService(t, registry, conf)
+	s.Start()
+	defer s.Stop()
+
+	wh, err := s.Register(server.URL, []string{"addr1"})
+	if err != nil {
+		t.Fatalf("failed to register webhook: %+v", err)
+	}
+
+	registry.Publish([]string{"addr1"}, map[string]string{"txid": "tx1"})
+
+	var deadLetters Deliveries
+	eventually(t, func() bool {
+		deadLetters, _ = s.DeadLetters(wh.ID)
+		return len(deadLetters) == 1
+	})
+
+	if n := int(attempts.Load()); n != conf.MaxAttempts {
+		t.Errorf("expected %d attempts, got %d", conf.MaxAttempts, n)
+	}
+
+	d := deadLetters[0]
+	if d.Attempts != conf.MaxAttempts || !strings.Contains(d.LastError, "500") {
+		t.Errorf("unexpected dead letter: %+v", d)
+	}
+
+	s.m.Lock()
+	pending := len(s.pending)
+	s.m.Unlock()
+
+	if pending != 0 {
+		t.Errorf("expected no pending deliveries, got %d", pending)
+	}
+
+	// redelivered deliveries start over with a full set of attempts
+	if err := s.Redeliver(wh.ID, d.ID); err != nil {
+		t.Fatalf("failed to redeliver: %+v", err)
+	}
+
+	eventually(t, func() bool {
+		deadLetters, _ = s.DeadLetters(wh.ID)
+		return len(deadLetters) == 1
+	})
+
+	if n := int(attempts.Load()); n != 2*conf.MaxAttempts {
+		t.Errorf("expected %d attempts, got %d", 2*conf.MaxAttempts, n)
+	}
+}
+
+func TestQueueFull(t *testing.T) {
+	conf := testConfig()
+	conf.QueueSize = 1
+
+	// not started, so nothing consumes the queue
+	s := newTestService(t, websocket.NewRegistry(), conf)
+
+	wh, err := s.Register("https://example.com/webhook", []string{"addr1"})
+	if err != nil {
+		t.Fatalf("failed to register webhook: %+v", err)
+	}
+
+	msg := []byte(`{"addresses":["addr1"],"data":{}}`)
+	s.handleMessage(wh.ID, msg)
+	s.handleMessage(wh.ID, msg)
+
+	deadLetters, err := s.DeadLetters(wh.ID)
+	if err != nil {
+		t.Fatalf("failed to get dead letters: %+v", err)
+	}
+
+	if len(deadLetters) != 1 || deadLetters[0].LastError != ErrQueueFull.Error() {
+		t.Fatalf("expected the second delivery to be dead lettered, got %+v", deadLetters)
+	}
+
+	err = s.Redeliver(wh.ID, deadLetters[0].ID)
+	if !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected %v, got %v", ErrQueueFull, err)
+	}
+
+	if e := api.AsAPIError(err); e.Status != http.StatusServiceUnavailable || e.Code != api.ErrorCodeUnavailable {
+		t.Errorf("expected %d %s, got %d %s", http.StatusServiceUnavailable, api.ErrorCodeUnavailable, e.Status, e.Code)
+	}
+
+	// the dead letter is kept if it could not be queued
+	if deadLetters, _ := s.DeadLetters(wh.ID); len(deadLetters) != 1 {
+		t.Errorf("expected dead letter to be kept, got %d", len(deadLetters))
+	}
+}
+
+func TestDialGuard(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	tests := []struct {
+		name string
+		url  string
+	}{
+		{name: "loopback", url: server.URL},
+		{name: "loopback ipv6", url: "http://[::1]:1"},
+		{name: "private", url: "http://10.0.0.1"},
+		{name: "private 192.168", url: "http://192.168.1.1"},
+		{name: "link local", url: "http://169.254.169.254/latest/meta-data"},
+		{name: "unspecified", url: "http://0.0.0.0"},
+	}
+
+	client := newHTTPClient(Config{Timeout: time.Second})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := client.Get(tt.url)
+			if err == nil {
+				res.Body.Close()
+				t.Fatalf("expected %s to be rejected", tt.url)
+			}
+
+			if !strings.Contains(err.Error(), "address not allowed") {
+				t.Errorf("expected address not allowed, got %v", err)
+			}
+		})
+	}
+
+	allowed := newHTTPClient(Config{Timeout: time.Second, AllowPrivate: true})
+
+	res, err := allowed.Get(server.URL)
+	if err != nil {
+		t.Fatalf("expected private address to be allowed: %v", err)
+	}
+	res.Body.Close()
+}
+
+func TestIsPublicIP(t *testing.T) {
+	tests := []struct {
+		ip       string
+		expected bool
+	}{
+		{ip: "8.8.8.8", expected: true},
+		{ip: "2001:4860:4860::8888", expected: true},
+		{ip: "127.0.0.1", expected: false},
+		{ip: "::1", expected: false},
+		{ip: "10.1.2.3", expected: false},
+		{ip: "172.16.0.1", expected: false},
+		{ip: "192.168.0.1", expected: false},
+		{ip: "fd00::1", expected: false},
+		{ip: "169.254.169.254", expected: false},
+		{ip: "fe80::1", expected: false},
+		{ip: "224.0.0.1", expected: false},
+		{ip: "0.0.0.0", expected: false},
+	}
+
+	for _, tt := range tests {
+		if public := isPublicIP(net.ParseIP(tt.ip)); public != tt.expected {
+			t.Errorf("%s: expected %t, got %t", tt.ip, tt.expected, public)
+		}
+	}
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	f := &fileStore{path: filepath.Join(t.TempDir(), "webhooks.json")}
+
+	empty, err := f.load()
+	if err != nil {
+		t.Fatalf("failed to load missing store: %+v", err)
+	}
+
+	if len(empty.Webhooks) != 0 || len(empty.Pending) != 0 || len(empty.DeadLetters) != 0 {
+		t.Errorf("expected empty snapshot, got %+v", empty)
+	}
+
+	expected := &snapshot{
+		Webhooks:    []*Webhook{{ID: "1", URL: "https://example.com/webhook", Addresses: []string{"addr1"}, Secret: "secret", CreatedAt: 1}},
+		Pending:     []*Delivery{{ID: "2", WebhookID: "1", Payload: json.RawMessage(`{"id":"2"}`), Attempts: 1, LastError: "failed", CreatedAt: 2, NextAttemptAt: 3}},
+		DeadLetters: []*Delivery{{ID: "3", WebhookID: "1", Payload: json.RawMessage(`{"id":"3"}`), Attempts: 8, CreatedAt: 4}},
+	}
+
+	if err := f.save(expected); err != nil {
+		t.Fatalf("failed to save store: %+v", err)
+	}
+
+	actual, err := f.load()
+	if err != nil {
+		t.Fatalf("failed to load store: %+v", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+
+	// temporary files are removed after replacing the store
+	if matches, _ := filepath.Glob(f.path + ".*.tmp"); len(matches) != 0 {
+		t.Errorf("expected no temporary files, got %v", matches)
+	}
+}
+
+func TestServiceRestore(t *testing.T) {
+	conf := testConfig()
+	conf.StorePath = filepath.Join(t.TempDir(), "webhooks.json")
+
+	s := newTestService(t, websocket.NewRegistry(), conf)
+
+	wh, err := s.Register("https://example.com/webhook", []string{"addr1"})
+	if err != nil {
+		t.Fatalf("failed to register webhook: %+v", err)
+	}
+
+	// the store is locked until stopped
+	if _, err := NewService(websocket.NewRegistry(), nil, metrics.NewPrometheus("test"), conf); err == nil {
+		t.Fatal("expected locked store to be rejected")
+	}
+
+	s.Stop()
+
+	registry := websocket.NewRegistry()
+	restored := newTestService(t, registry, conf)
+	defer restored.Stop()
+
+	actual, err := restored.Get(wh.ID)
+	if err != nil {
+		t.Fatalf("expected webhook to be restored: %+v", err)
+	}
+
+	if actual.URL != wh.URL || !reflect.DeepEqual(actual.Addresses, wh.Addresses) {
+		t.Errorf("expected %+v, got %+v", wh, actual)
+	}
+
+	// restored webhooks are subscribed for their addresses
+	registry.Publish([]string{"addr1"}, map[string]string{"txid": "tx1"})
+
+	restored.m.Lock()
+	pending := len(restored.pending)
+	restored.m.Unlock()
+
+	if pending != 1 {
+		t.Errorf("expected 1 pending delivery, got %d", pending)
+	}
+}
+
+func TestServiceDisabled(t *testing.T) {
+	conf := testConfig()
+	conf.Replicated = true
+
+	s := newTestService(t, websocket.NewRegistry(), conf)
+	s.Start()
+	defer s.Stop()
+
+	if _, err := s.Register("https://example.com/webhook", []string{"addr1"}); !errors.Is(err, ErrDisabled) {
+		t.Errorf("expected %v, got %v", ErrDisabled, err)
+	}
+
+	if _, err := s.Get("1"); !errors.Is(err, ErrDisabled) {
+		t.Errorf("expected %v, got %v", ErrDisabled, err)
+	}
+}
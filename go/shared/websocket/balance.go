@@ -0,0 +1,93 @@
+package websocket
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// max number of balances fetched concurrently
+	balanceConcurrency = 10
+	// max time to fetch the balance of an address
+	balanceTimeout = 10 * time.Second
+)
+
+// BalanceHandlerFunc returns the current balance data of an address
+type BalanceHandlerFunc = func(ctx context.Context, addr string) (interface{}, error)
+
+// BalanceHandler sets the handler used to fetch the updated balance of an address for balance subscriptions
+func (r *Registry) BalanceHandler(fn BalanceHandlerFunc) {
+	r.balancesM.Lock()
+	defer r.balancesM.Unlock()
+	r.balanceHandler = fn
+}
+
+// trackBalance queues the address to have its updated balance pushed once the next block is delivered, regardless of how many txs are published for it in the meantime
+func (r *Registry) trackBalance(addr string) {
+	r.balancesM.Lock()
+	defer r.balancesM.Unlock()
+
+	if r.balanceHandler == nil {
+		return
+	}
+
+	r.pendingBalances[addr] = struct{}{}
+}
+
+// deliverBalances fetches the balance of each queued address and sends it to all balance subscriptions for the address.
+// Called for each new block, so the balance of an address is sent at most once per block after the node has processed all txs of the previous block.
+func (r *Registry) deliverBalances() {
+	r.balancesM.Lock()
+	addrs := r.pendingBalances
+	handler := r.balanceHandler
+	r.pendingBalances = make(map[string]struct{})
+	r.balancesM.Unlock()
+
+	if len(addrs) == 0 {
+		return
+	}
+
+	g := new(errgroup.Group)
+	g.SetLimit(balanceConcurrency)
+
+	for addr := range addrs {
+		g.Go(func() error {
+			ctx, cancel := context.WithTimeout(r.ctx, balanceTimeout)
+			defer cancel()
+
+			data, err := handler(ctx, addr)
+			if err != nil {
+				logger.Errorf("failed to handle balance: %+v", err)
+				return nil
+			}
+
+			s := r.shard(addr)
+
+			// copy subscriptions so no lock is held while sending
+			s.m.RLock()
+			subscriptions := make(map[string]subscription, len(s.addresses[addr]))
+			for id, sub := range s.addresses[addr] {
+				if sub.opts.Balances {
+					subscriptions[id] = sub
+				}
+			}
+			s.m.RUnlock()
+
+			payload := NewData(data)
+
+			for id, sub := range subscriptions {
+				_, subscriptionID := fromID(id)
+
+				logger.Debugf("PublishBalance: subscriptionID: %s, address: %s", subscriptionID, addr)
+
+				sub.subscriber.Send(NewDataMessage(MessageResponse{Address: addr, SubscriptionID: subscriptionID, Topic: TopicBalances}, payload))
+			}
+
+			return nil
+		})
+	}
+
+	_ = g.Wait()
+}
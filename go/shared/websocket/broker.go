@@ -0,0 +1,124 @@
+package websocket
+
+import (
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	DEFAULT_BROKER_SUBJECT = "unchained"
+	DEFAULT_BROKER_INGEST  = true
+)
+
+// BrokerMessage is a message published to the broker for delivery to subscribed clients on every registry
+type BrokerMessage struct {
+	Addresses []string    `json:"addresses,omitempty"`
+	Data      interface{} `json:"data"`
+	Topic     string      `json:"topic,omitempty"`
+}
+
+// BrokerHandlerFunc is called for every message published to the broker
+type BrokerHandlerFunc = func(msg BrokerMessage)
+
+// Broker distributes published messages to all registries subscribed to it.
+// This allows a single ingest process to fan out messages to many api replicas.
+type Broker interface {
+	Publish(msg BrokerMessage) error
+	Subscribe(handler BrokerHandlerFunc) error
+	Close() error
+}
+
+// BrokerConfig for the broker backing a registry
+type BrokerConfig struct {
+	// url of the network broker, use the in memory broker if empty
+	URL string
+	// subject messages are published to on the network broker
+	Subject string
+	// subscribe to the upstream node and publish messages to the broker.
+	// if false, messages are only received from the broker.
+	Ingest bool
+}
+
+// BrokerConfigFromEnv loads any optional broker config from the environment, falling back to defaults
+func BrokerConfigFromEnv() BrokerConfig {
+	conf := BrokerConfig{
+		URL:     os.Getenv("BROKER_URL"),
+		Subject: DEFAULT_BROKER_SUBJECT,
+		Ingest:  DEFAULT_BROKER_INGEST,
+	}
+
+	if subject := os.Getenv("BROKER_SUBJECT"); subject != "" {
+		conf.Subject = subject
+	}
+
+	if ingest := os.Getenv("BROKER_INGEST"); ingest != "" {
+		if i, err := strconv.ParseBool(ingest); err == nil {
+			conf.Ingest = i
+		} else {
+			logger.Warnf("invalid BROKER_INGEST: %s (defaulting to %t)", ingest, DEFAULT_BROKER_INGEST)
+		}
+	}
+
+	return conf
+}
+
+// NewBroker creates a network broker if a url is configured, otherwise an in memory broker
+func NewBroker(conf BrokerConfig) (Broker, error) {
+	if conf.URL == "" {
+		if !conf.Ingest {
+			return nil, errors.New("BROKER_URL required if BROKER_INGEST is disabled")
+		}
+
+		return NewMemoryBroker(), nil
+	}
+
+	broker, err := NewNATSBroker(conf.URL, conf.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	return broker, nil
+}
+
+// MemoryBroker delivers published messages synchronously to all handlers in the same process
+type MemoryBroker struct {
+	handlers []BrokerHandlerFunc
+	m        sync.RWMutex
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{}
+}
+
+func (b *MemoryBroker) Publish(msg BrokerMessage) error {
+	b.m.RLock()
+	handlers := b.handlers
+	b.m.RUnlock()
+
+	for _, handler := range handlers {
+		handler(msg)
+	}
+
+	return nil
+}
+
+func (b *MemoryBroker) Subscribe(handler BrokerHandlerFunc) error {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	b.handlers = append(b.handlers, handler)
+
+	return nil
+}
+
+func (b *MemoryBroker) Close() error {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	b.handlers = nil
+
+	return nil
+}
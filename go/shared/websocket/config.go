@@ -0,0 +1,73 @@
+package websocket
+
+import (
+	"os"
+	"time"
+
+	"github.com/shapeshift/unchained/shared/config"
+)
+
+type OverflowPolicy string
+
+const (
+	// OverflowDropOldest drops the oldest queued message to make room for the newest message
+	OverflowDropOldest OverflowPolicy = "drop_oldest"
+	// OverflowDisconnect closes the connection when the client is unable to keep up
+	OverflowDisconnect OverflowPolicy = "disconnect"
+)
+
+const (
+	DEFAULT_QUEUE_SIZE               = 256
+	DEFAULT_QUEUE_OVERFLOW_POLICY    = OverflowDropOldest
+	DEFAULT_MAX_ADDRESSES            = 100
+	DEFAULT_MAX_CONNECTION_ADDRESSES = 1000
+	DEFAULT_MAX_SUBSCRIPTIONS        = 100
+	DEFAULT_MAX_CONNECTIONS_PER_IP   = 50
+	DEFAULT_SUBSCRIBE_RATE           = 10
+	DEFAULT_DRAIN_TIMEOUT            = 10 * time.Second
+)
+
+// Config for websocket client connections
+type Config struct {
+	// max number of outbound messages queued per connection
+	QueueSize int
+	// action taken when a connection's outbound queue is full
+	QueueOverflowPolicy OverflowPolicy
+	// max number of addresses per subscription
+	MaxAddresses int
+	// max number of addresses across all subscriptions per connection
+	MaxConnectionAddresses int
+	// max number of subscriptions per connection
+	MaxSubscriptions int
+	// max number of concurrent connections per client ip
+	MaxConnectionsPerIP int
+	// max number of subscribe requests per second per connection
+	SubscribeRate int
+	// max time to wait for clients to disconnect when draining connections on shutdown
+	DrainTimeout time.Duration
+}
+
+// ConfigFromEnv loads any optional websocket config from the environment, falling back to defaults
+func ConfigFromEnv() Config {
+	conf := Config{
+		QueueSize:              config.IntFromEnv("WS_QUEUE_SIZE", DEFAULT_QUEUE_SIZE, 1),
+		QueueOverflowPolicy:    DEFAULT_QUEUE_OVERFLOW_POLICY,
+		MaxAddresses:           config.IntFromEnv("WS_MAX_ADDRESSES", DEFAULT_MAX_ADDRESSES, 1),
+		MaxConnectionAddresses: config.IntFromEnv("WS_MAX_CONNECTION_ADDRESSES", DEFAULT_MAX_CONNECTION_ADDRESSES, 1),
+		MaxSubscriptions:       config.IntFromEnv("WS_MAX_SUBSCRIPTIONS", DEFAULT_MAX_SUBSCRIPTIONS, 1),
+		MaxConnectionsPerIP:    config.IntFromEnv("WS_MAX_CONNECTIONS_PER_IP", DEFAULT_MAX_CONNECTIONS_PER_IP, 1),
+		SubscribeRate:          config.IntFromEnv("WS_SUBSCRIBE_RATE", DEFAULT_SUBSCRIBE_RATE, 1),
+		DrainTimeout:           config.DurationFromEnv("WS_DRAIN_TIMEOUT", DEFAULT_DRAIN_TIMEOUT),
+	}
+
+	if policy := os.Getenv("WS_QUEUE_OVERFLOW_POLICY"); policy != "" {
+		switch p := OverflowPolicy(policy); p {
+		case OverflowDropOldest, OverflowDisconnect:
+			conf.QueueOverflowPolicy = p
+		default:
+			logger.Warnf("invalid WS_QUEUE_OVERFLOW_POLICY: %s (defaulting to %s)", policy, DEFAULT_QUEUE_OVERFLOW_POLICY)
+		}
+	}
+
+	return conf
+}
@@ -0,0 +1,254 @@
+package websocket
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+	"github.com/shamaton/msgpack/v2"
+)
+
+// Encoding of the messages sent to a client, negotiated using the websocket subprotocol at upgrade time
+type Encoding string
+
+const (
+	EncodingJSON    Encoding = "json"
+	EncodingMsgpack Encoding = "msgpack"
+)
+
+const (
+	SubprotocolJSON    = "unchained.json"
+	SubprotocolMsgpack = "unchained.msgpack"
+)
+
+// Subprotocols supported by the server in order of preference.
+// Clients not requesting a subprotocol receive json text messages.
+// The "pong" reply to a "ping" heartbeat request is the only message always sent as a text frame regardless of encoding.
+var Subprotocols = []string{SubprotocolMsgpack, SubprotocolJSON}
+
+// EncodingFromSubprotocol returns the message encoding for the subprotocol negotiated with the client
+func EncodingFromSubprotocol(subprotocol string) Encoding {
+	switch subprotocol {
+	case SubprotocolMsgpack:
+		return EncodingMsgpack
+	default:
+		return EncodingJSON
+	}
+}
+
+// Data is the data of a published message shared by every client it is sent to, encoded at most once per encoding
+type Data struct {
+	value       interface{}
+	jsonOnce    sync.Once
+	json        json.RawMessage
+	jsonErr     error
+	msgpackOnce sync.Once
+	msgpack     []byte
+	msgpackErr  error
+}
+
+func NewData(value interface{}) *Data {
+	return &Data{value: value}
+}
+
+// JSON returns the json encoded data
+func (d *Data) JSON() (json.RawMessage, error) {
+	d.jsonOnce.Do(func() {
+		if raw, ok := d.value.(json.RawMessage); ok {
+			d.json = raw
+			return
+		}
+
+		d.json, d.jsonErr = json.Marshal(d.value)
+		d.jsonErr = errors.Wrap(d.jsonErr, "failed to encode json data")
+	})
+
+	return d.json, d.jsonErr
+}
+
+// Msgpack returns the msgpack encoded data
+func (d *Data) Msgpack() ([]byte, error) {
+	d.msgpackOnce.Do(func() {
+		raw, err := d.JSON()
+		if err != nil {
+			d.msgpackErr = err
+			return
+		}
+
+		d.msgpack, d.msgpackErr = jsonToMsgpack(raw)
+	})
+
+	return d.msgpack, d.msgpackErr
+}
+
+// Message is a message queued for delivery to a client, encoded using the encoding negotiated by the client when written.
+// A message is either a json message sent to a single client, or published data along with the envelope of a single subscription,
+// in which case only the envelope is encoded per client.
+type Message struct {
+	json     []byte
+	response MessageResponse
+	data     *Data
+}
+
+// NewMessage creates a message from a json message
+func NewMessage(msg []byte) *Message {
+	return &Message{json: msg}
+}
+
+// NewDataMessage creates a message for the subscription response provided containing the published data
+func NewDataMessage(response MessageResponse, data *Data) *Message {
+	response.Data = nil
+	return &Message{response: response, data: data}
+}
+
+// JSON returns the json encoded message
+func (m *Message) JSON() ([]byte, error) {
+	if m.data == nil {
+		return m.json, nil
+	}
+
+	data, err := m.data.JSON()
+	if err != nil {
+		return nil, err
+	}
+
+	r := m.response
+	r.Data = data
+
+	msg, err := json.Marshal(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode json message")
+	}
+
+	return msg, nil
+}
+
+// Encode the message using the encoding provided, returning the websocket message type to send it as
+func (m *Message) Encode(encoding Encoding) (int, []byte, error) {
+	if encoding != EncodingMsgpack || m.data == nil {
+		msg, err := m.JSON()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		return encode(encoding, msg)
+	}
+
+	data, err := m.data.Msgpack()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	msg, err := msgpackResponse(m.response, data)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return websocket.BinaryMessage, msg, nil
+}
+
+// msgpackResponse encodes the envelope of a subscription response as a msgpack map with the already encoded data spliced in.
+// keys match the json encoding of MessageResponse.
+func msgpackResponse(r MessageResponse, data []byte) ([]byte, error) {
+	keys := []string{}
+	values := []interface{}{}
+
+	if r.Address != "" {
+		keys, values = append(keys, "address"), append(values, r.Address)
+	}
+	if len(r.Addresses) > 0 {
+		keys, values = append(keys, "addresses"), append(values, r.Addresses)
+	}
+	keys, values = append(keys, "subscriptionId"), append(values, r.SubscriptionID)
+	if r.Topic != "" {
+		keys, values = append(keys, "topic"), append(values, r.Topic)
+	}
+
+	buf := bytes.Buffer{}
+
+	// map header including the data key
+	if n := len(keys) + 1; n < 16 {
+		buf.WriteByte(0x80 | byte(n))
+	} else {
+		buf.WriteByte(0xde)
+		_ = binary.Write(&buf, binary.BigEndian, uint16(n))
+	}
+
+	for i, k := range keys {
+		for _, v := range []interface{}{k, values[i]} {
+			b, err := msgpack.Marshal(v)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to encode msgpack message")
+			}
+			buf.Write(b)
+		}
+	}
+
+	key, err := msgpack.Marshal("data")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode msgpack message")
+	}
+	buf.Write(key)
+	buf.Write(data)
+
+	return buf.Bytes(), nil
+}
+
+// encode a json message using the encoding provided, returning the websocket message type to send it as
+func encode(encoding Encoding, msg []byte) (int, []byte, error) {
+	if encoding != EncodingMsgpack {
+		return websocket.TextMessage, msg, nil
+	}
+
+	data, err := jsonToMsgpack(msg)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return websocket.BinaryMessage, data, nil
+}
+
+// jsonToMsgpack re-encodes json as msgpack
+func jsonToMsgpack(msg []byte) ([]byte, error) {
+	d := json.NewDecoder(bytes.NewReader(msg))
+	d.UseNumber()
+
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return nil, errors.Wrap(err, "failed to decode json message")
+	}
+
+	data, err := msgpack.Marshal(msgpackNumbers(v))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode msgpack message")
+	}
+
+	return data, nil
+}
+
+// msgpackNumbers converts json numbers into integers where possible so they are encoded compactly
+func msgpackNumbers(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, e := range t {
+			t[k] = msgpackNumbers(e)
+		}
+	case []interface{}:
+		for i, e := range t {
+			t[i] = msgpackNumbers(e)
+		}
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i
+		}
+		if f, err := t.Float64(); err == nil {
+			return f
+		}
+		return t.String()
+	}
+
+	return v
+}
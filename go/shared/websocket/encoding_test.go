@@ -0,0 +1,245 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/shamaton/msgpack/v2"
+	"github.com/shapeshift/unchained/shared/metrics"
+)
+
+// newTestServer serves websocket connections subscribed to the registry provided, negotiating the supported subprotocols
+func newTestServer(t *testing.T, registry *Registry, manager *Manager) *httptest.Server {
+	t.Helper()
+
+	go manager.Start()
+
+	upgrader := websocket.Upgrader{Subprotocols: Subprotocols}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+
+		NewConnection(conn, registry, manager).Start()
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func dial(t *testing.T, server *httptest.Server, subprotocols []string) *websocket.Conn {
+	t.Helper()
+
+	dialer := websocket.Dialer{Subprotocols: subprotocols}
+
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("failed to dial: %+v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+// readMessage reads the next message, decoding it using the encoding expected for the message type
+func readMessage(t *testing.T, conn *websocket.Conn, expected int) map[string]interface{} {
+	t.Helper()
+
+	if err := conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatalf("failed to set read deadline: %+v", err)
+	}
+
+	messageType, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("failed to read message: %+v", err)
+	}
+
+	if messageType != expected {
+		t.Fatalf("expected message type %d, got %d", expected, messageType)
+	}
+
+	msg := map[string]interface{}{}
+	if messageType == websocket.BinaryMessage {
+		if err := msgpack.Unmarshal(data, &msg); err != nil {
+			t.Fatalf("failed to decode msgpack message: %+v", err)
+		}
+	} else {
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.Fatalf("failed to decode json message: %+v", err)
+		}
+	}
+
+	return msg
+}
+
+// waitForSubscription fails the test if no subscription is registered for the address before the timeout
+func waitForSubscription(t *testing.T, registry *Registry, addr string) {
+	t.Helper()
+
+	s := registry.shard(addr)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		s.m.RLock()
+		n := len(s.addresses[addr])
+		s.m.RUnlock()
+
+		if n > 0 {
+			return
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("expected subscription for address %s", addr)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSubprotocolNegotiation(t *testing.T) {
+	tests := []struct {
+		name         string
+		subprotocols []string
+		expected     string
+		messageType  int
+	}{
+		{name: "none", messageType: websocket.TextMessage},
+		{name: "json", subprotocols: []string{SubprotocolJSON}, expected: SubprotocolJSON, messageType: websocket.TextMessage},
+		{name: "msgpack", subprotocols: []string{SubprotocolMsgpack}, expected: SubprotocolMsgpack, messageType: websocket.BinaryMessage},
+		{name: "server preference", subprotocols: []string{SubprotocolJSON, SubprotocolMsgpack}, expected: SubprotocolMsgpack, messageType: websocket.BinaryMessage},
+		{name: "unsupported", subprotocols: []string{"unchained.cbor"}, messageType: websocket.TextMessage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := NewRegistry()
+			conn := dial(t, newTestServer(t, registry, NewManager(metrics.NewPrometheus("test"))), tt.subprotocols)
+
+			if conn.Subprotocol() != tt.expected {
+				t.Fatalf("expected subprotocol %q, got %q", tt.expected, conn.Subprotocol())
+			}
+
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(`{"method":"subscribe","subscriptionId":"1","data":{"topic":"txs","addresses":["addr1"]}}`)); err != nil {
+				t.Fatalf("failed to write message: %+v", err)
+			}
+
+			if msg := readMessage(t, conn, tt.messageType); msg["type"] != "subscribed" || msg["subscriptionId"] != "1" {
+				t.Fatalf("unexpected subscription response: %v", msg)
+			}
+
+			// the subscription is acknowledged before it is registered
+			waitForSubscription(t, registry, "addr1")
+
+			registry.Publish([]string{"addr1"}, map[string]interface{}{"txid": "txid1", "blockHeight": 100})
+
+			msg := readMessage(t, conn, tt.messageType)
+			if msg["address"] != "addr1" || msg["subscriptionId"] != "1" {
+				t.Fatalf("unexpected message: %v", msg)
+			}
+
+			// nested msgpack maps are decoded with interface keys, compare the printed data of either encoding
+			if data := fmt.Sprint(msg["data"]); data != "map[blockHeight:100 txid:txid1]" {
+				t.Fatalf("unexpected message data: %s", data)
+			}
+
+			// the heartbeat reply is always a text frame
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(`{"method":"ping"}`)); err != nil {
+				t.Fatalf("failed to write message: %+v", err)
+			}
+
+			messageType, pong, err := conn.ReadMessage()
+			if err != nil || messageType != websocket.TextMessage || string(pong) != "pong" {
+				t.Fatalf("expected pong text message, got %d %q: %v", messageType, pong, err)
+			}
+		})
+	}
+}
+
+func TestMessageEncodingMatchesJSON(t *testing.T) {
+	data := NewData(map[string]interface{}{"txid": "txid1", "blockHeight": 100, "fee": 0.5, "transfers": []interface{}{"a", "b"}})
+
+	tests := []struct {
+		name     string
+		response MessageResponse
+	}{
+		{name: "address", response: MessageResponse{Address: "addr1", SubscriptionID: "1"}},
+		{name: "addresses", response: MessageResponse{Addresses: []string{"addr1", "addr2"}, SubscriptionID: "1"}},
+		{name: "topic", response: MessageResponse{SubscriptionID: "1", Topic: TopicBlocks}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := NewDataMessage(tt.response, data)
+
+			raw, err := msg.JSON()
+			if err != nil {
+				t.Fatalf("failed to encode json message: %+v", err)
+			}
+
+			// the msgpack message spliced from the encoded data must match re-encoding the full json message
+			_, expected, err := encode(EncodingMsgpack, raw)
+			if err != nil {
+				t.Fatalf("failed to encode message: %+v", err)
+			}
+
+			messageType, actual, err := msg.Encode(EncodingMsgpack)
+			if err != nil {
+				t.Fatalf("failed to encode message: %+v", err)
+			}
+
+			if messageType != websocket.BinaryMessage {
+				t.Fatalf("expected binary message, got %d", messageType)
+			}
+
+			var e, a map[string]interface{}
+			if err := msgpack.Unmarshal(expected, &e); err != nil {
+				t.Fatalf("failed to decode msgpack message: %+v", err)
+			}
+			if err := msgpack.Unmarshal(actual, &a); err != nil {
+				t.Fatalf("failed to decode msgpack message: %+v", err)
+			}
+
+			if !reflect.DeepEqual(e, a) {
+				t.Fatalf("expected %v, got %v", e, a)
+			}
+		})
+	}
+}
+
+// countingValue counts the number of times it is encoded as json
+type countingValue struct {
+	count *atomic.Int32
+}
+
+func (v countingValue) MarshalJSON() ([]byte, error) {
+	v.count.Add(1)
+	return []byte(`{"txid":"txid1"}`), nil
+}
+
+func TestDataEncodedOncePerEncoding(t *testing.T) {
+	count := &atomic.Int32{}
+	data := NewData(countingValue{count: count})
+
+	for i := 0; i < 10; i++ {
+		msg := NewDataMessage(MessageResponse{Address: "addr1", SubscriptionID: "1"}, data)
+
+		for _, encoding := range []Encoding{EncodingJSON, EncodingMsgpack} {
+			if _, _, err := msg.Encode(encoding); err != nil {
+				t.Fatalf("failed to encode message: %+v", err)
+			}
+		}
+	}
+
+	if n := count.Load(); n != 1 {
+		t.Fatalf("expected data to be encoded once, got %d", n)
+	}
+}
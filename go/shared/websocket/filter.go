@@ -0,0 +1,112 @@
+package websocket
+
+import (
+	"encoding/json"
+	"math/big"
+	"slices"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	DirectionIn  = "in"
+	DirectionOut = "out"
+)
+
+// Filter restricts the messages delivered for an address subscription to txs with at least one tx message matching all of the criteria provided
+type Filter struct {
+	// tx message types to match (ex. send, delegate, outbound), any type if empty
+	Types []string `json:"types,omitempty"`
+	// denom of the tx message value, any denom if empty
+	Denom string `json:"denom,omitempty"`
+	// min amount of the tx message value (inclusive) in base units, any amount if empty
+	MinAmount string `json:"minAmount,omitempty"`
+	// direction of the tx message relative to the subscribed address: in (to) or out (from), any direction if empty
+	Direction string `json:"direction,omitempty"`
+	minAmount *big.Int
+}
+
+// filterData is the subset of published message data evaluated by a filter or balance subscription
+type filterData struct {
+	BlockHeight *int `json:"blockHeight"`
+	Messages    []struct {
+		From  string `json:"from"`
+		To    string `json:"to"`
+		Type  string `json:"type"`
+		Value struct {
+			Amount string `json:"amount"`
+			Denom  string `json:"denom"`
+		} `json:"value"`
+	} `json:"messages"`
+}
+
+// Validate the filter criteria, which must be called before the filter is used for matching
+func (f *Filter) Validate() error {
+	if f.MinAmount != "" {
+		minAmount, ok := new(big.Int).SetString(f.MinAmount, 10)
+		if !ok || minAmount.Sign() < 0 {
+			return errors.Errorf("invalid minAmount: %s", f.MinAmount)
+		}
+		f.minAmount = minAmount
+	}
+
+	switch f.Direction {
+	case "", DirectionIn, DirectionOut:
+	default:
+		return errors.Errorf("invalid direction: %s (expected: %s or %s)", f.Direction, DirectionIn, DirectionOut)
+	}
+
+	return nil
+}
+
+// parseFilterData extracts the tx messages from published message data,
+// which is either the original value or raw json if received through a network broker
+func parseFilterData(data interface{}) *filterData {
+	raw, ok := data.(json.RawMessage)
+	if !ok {
+		var err error
+		if raw, err = json.Marshal(data); err != nil {
+			logger.Errorf("failed to marshal message data for filtering: %v", err)
+			return &filterData{}
+		}
+	}
+
+	// message data is not required to be a tx, in which case there are no tx messages to match
+	fd := &filterData{}
+	_ = json.Unmarshal(raw, fd)
+
+	return fd
+}
+
+// confirmed returns false for pending txs, which have a negative block height, as they have not changed any balances
+func (fd *filterData) confirmed() bool {
+	return fd.BlockHeight == nil || *fd.BlockHeight >= 0
+}
+
+// match returns true if any tx message matches all of the filter criteria for the subscribed address
+func (f *Filter) match(addr string, fd *filterData) bool {
+	for _, m := range fd.Messages {
+		if len(f.Types) > 0 && !slices.Contains(f.Types, m.Type) {
+			continue
+		}
+
+		if f.Denom != "" && m.Value.Denom != f.Denom {
+			continue
+		}
+
+		if f.Direction == DirectionIn && m.To != addr || f.Direction == DirectionOut && m.From != addr {
+			continue
+		}
+
+		if f.minAmount != nil {
+			amount, ok := new(big.Int).SetString(m.Value.Amount, 10)
+			if !ok || amount.Cmp(f.minAmount) < 0 {
+				continue
+			}
+		}
+
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,98 @@
+package websocket
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// tx with a send from addr1 to addr2 and a delegate from addr2
+const filterTx = `{
+	"txid": "txid1",
+	"blockHeight": 100,
+	"messages": [
+		{"from": "addr1", "to": "addr2", "type": "send", "value": {"amount": "1000", "denom": "uatom"}},
+		{"from": "addr2", "to": "validator1", "type": "delegate", "value": {"amount": "500", "denom": "uosmo"}}
+	]
+}`
+
+func TestFilterMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   Filter
+		addr     string
+		expected bool
+	}{
+		{name: "empty filter", filter: Filter{}, addr: "addr1", expected: true},
+		{name: "type", filter: Filter{Types: []string{"delegate"}}, addr: "addr2", expected: true},
+		{name: "any of types", filter: Filter{Types: []string{"outbound", "send"}}, addr: "addr1", expected: true},
+		{name: "type mismatch", filter: Filter{Types: []string{"outbound"}}, addr: "addr1", expected: false},
+		{name: "denom", filter: Filter{Denom: "uosmo"}, addr: "addr2", expected: true},
+		{name: "denom mismatch", filter: Filter{Denom: "rune"}, addr: "addr1", expected: false},
+		{name: "min amount inclusive", filter: Filter{MinAmount: "1000"}, addr: "addr1", expected: true},
+		{name: "min amount of any message", filter: Filter{MinAmount: "600"}, addr: "addr2", expected: true},
+		{name: "below min amount", filter: Filter{MinAmount: "1001"}, addr: "addr1", expected: false},
+		{name: "min amount exceeding int64", filter: Filter{MinAmount: "100000000000000000000"}, addr: "addr1", expected: false},
+		{name: "direction in", filter: Filter{Direction: DirectionIn}, addr: "addr2", expected: true},
+		{name: "direction in mismatch", filter: Filter{Direction: DirectionIn}, addr: "addr1", expected: false},
+		{name: "direction out", filter: Filter{Direction: DirectionOut}, addr: "addr1", expected: true},
+		{name: "direction out mismatch", filter: Filter{Direction: DirectionOut}, addr: "validator1", expected: false},
+		// criteria must all match the same message
+		{name: "all criteria", filter: Filter{Types: []string{"send"}, Denom: "uatom", MinAmount: "1000", Direction: DirectionIn}, addr: "addr2", expected: true},
+		{name: "criteria split across messages", filter: Filter{Types: []string{"delegate"}, Denom: "uatom"}, addr: "addr2", expected: false},
+		{name: "direction of other message", filter: Filter{Types: []string{"send"}, Direction: DirectionOut}, addr: "addr2", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.filter.Validate(); err != nil {
+				t.Fatalf("invalid filter: %+v", err)
+			}
+
+			// data is parsed the same whether published as a value or received as raw json through a network broker
+			var value interface{}
+			if err := json.Unmarshal([]byte(filterTx), &value); err != nil {
+				t.Fatalf("failed to unmarshal tx: %+v", err)
+			}
+
+			for _, data := range []interface{}{value, json.RawMessage(filterTx)} {
+				if matched := tt.filter.match(tt.addr, parseFilterData(data)); matched != tt.expected {
+					t.Errorf("expected match %t, got %t (%T)", tt.expected, matched, data)
+				}
+			}
+		})
+	}
+}
+
+func TestFilterMatchNonTx(t *testing.T) {
+	f := &Filter{}
+	if err := f.Validate(); err != nil {
+		t.Fatalf("invalid filter: %+v", err)
+	}
+
+	// message data without tx messages never matches a filter
+	if f.match("addr1", parseFilterData(map[string]interface{}{"balance": "100"})) {
+		t.Error("expected data without tx messages not to match")
+	}
+}
+
+func TestFilterValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+		valid  bool
+	}{
+		{name: "empty", filter: Filter{}, valid: true},
+		{name: "min amount", filter: Filter{MinAmount: "0"}, valid: true},
+		{name: "negative min amount", filter: Filter{MinAmount: "-1"}, valid: false},
+		{name: "decimal min amount", filter: Filter{MinAmount: "1.5"}, valid: false},
+		{name: "invalid direction", filter: Filter{Direction: "both"}, valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.filter.Validate(); (err == nil) != tt.valid {
+				t.Errorf("expected valid %t, got: %v", tt.valid, err)
+			}
+		})
+	}
+}
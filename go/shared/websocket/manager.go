@@ -1,18 +1,39 @@
 package websocket
 
-import "github.com/shapeshift/unchained/shared/metrics"
+import (
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/shapeshift/unchained/shared/metrics"
+)
+
+// interval at which the remaining connection count is checked while draining
+const drainPollInterval = 100 * time.Millisecond
 
 // Manager manages registering, unregistering, and signaling cleanup of client connections
 type Manager struct {
+	config      Config
 	connections map[*Connection]bool
-	register    chan *Connection
-	unregister  chan *Connection
-	prometheus  *metrics.Prometheus
+	draining    bool
+	streams     map[*Stream]struct{}
+	// client ip to number of connections
+	ips map[string]int
+	// accepted connection to client ip
+	connIPs    map[*websocket.Conn]string
+	register   chan *Connection
+	unregister chan *Connection
+	prometheus *metrics.Prometheus
+	m          sync.RWMutex
 }
 
 func NewManager(prometheus *metrics.Prometheus) *Manager {
 	return &Manager{
+		config:      ConfigFromEnv(),
 		connections: make(map[*Connection]bool),
+		ips:         make(map[string]int),
+		connIPs:     make(map[*websocket.Conn]string),
+		streams:     make(map[*Stream]struct{}),
 		register:    make(chan *Connection),
 		unregister:  make(chan *Connection),
 		prometheus:  prometheus,
@@ -23,16 +44,151 @@ func (m *Manager) Start() {
 	for {
 		select {
 		case c := <-m.register:
+			m.m.Lock()
 			m.connections[c] = true
+			draining := m.draining
+			m.m.Unlock()
 			m.prometheus.Metrics.WebsocketCount.Inc()
+
+			// connection was accepted before draining started
+			if draining {
+				go c.goAway()
+			}
 		case c := <-m.unregister:
+			m.m.Lock()
+			_, ok := m.connections[c]
 			delete(m.connections, c)
-			close(c.doneChan)
-			m.prometheus.Metrics.WebsocketCount.Dec()
+			m.release(c.conn)
+			m.m.Unlock()
+
+			// ensure connection has not already been unregistered before signaling cleanup
+			if ok {
+				close(c.doneChan)
+				m.prometheus.Metrics.WebsocketCount.Dec()
+			}
 		}
 	}
 }
 
+// Accept a newly upgraded connection from the client ip if the connection limit for the ip has not been reached.
+// If the limit has been reached, the connection is closed and false is returned.
+func (m *Manager) Accept(conn *websocket.Conn, ip string) bool {
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	if m.draining {
+		msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, goingAwayReason)
+		_ = conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(writeWait))
+		_ = conn.Close()
+		return false
+	}
+
+	if m.ips[ip] >= m.config.MaxConnectionsPerIP {
+		logger.Warnf("too many connections from ip: %s", ip)
+		m.prometheus.Metrics.WebsocketLimitExceeded.With(metrics.Labels{"limit": "connections_per_ip"}).Inc()
+		msg := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "too many connections")
+		_ = conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(writeWait))
+		_ = conn.Close()
+		return false
+	}
+
+	m.ips[ip]++
+	m.connIPs[conn] = ip
+
+	return true
+}
+
+// release the connection from its client ip. expects the caller to hold the lock.
+func (m *Manager) release(conn *websocket.Conn) {
+	ip, ok := m.connIPs[conn]
+	if !ok {
+		return
+	}
+
+	delete(m.connIPs, conn)
+
+	if m.ips[ip]--; m.ips[ip] <= 0 {
+		delete(m.ips, ip)
+	}
+}
+
 func (m *Manager) ConnectionCount() int {
+	m.m.RLock()
+	defer m.m.RUnlock()
 	return len(m.connections)
 }
+
+// Draining returns true once the manager has started draining connections on shutdown
+func (m *Manager) Draining() bool {
+	m.m.RLock()
+	defer m.m.RUnlock()
+	return m.draining
+}
+
+// Drain all client connections on shutdown so clients can reconnect to another server without error.
+// Every connection is sent a going away close frame and every stream is ended, after which any new connections or subscriptions are rejected.
+// Clients are given up to the drain timeout to disconnect before any remaining connections are closed.
+func (m *Manager) Drain() {
+	m.m.Lock()
+	m.draining = true
+	connections := make([]*Connection, 0, len(m.connections))
+	for c := range m.connections {
+		connections = append(connections, c)
+	}
+	streams := make([]*Stream, 0, len(m.streams))
+	for s := range m.streams {
+		streams = append(streams, s)
+	}
+	m.m.Unlock()
+
+	logger.Infof("draining %d websocket connections and %d streams", len(connections), len(streams))
+
+	for _, c := range connections {
+		go c.goAway()
+	}
+
+	for _, s := range streams {
+		s.stop()
+	}
+
+	deadline := time.Now().Add(m.config.DrainTimeout)
+	for m.ConnectionCount() > 0 && time.Now().Before(deadline) {
+		time.Sleep(drainPollInterval)
+	}
+
+	m.m.RLock()
+	remaining := make([]*Connection, 0, len(m.connections))
+	for c := range m.connections {
+		remaining = append(remaining, c)
+	}
+	m.m.RUnlock()
+
+	if len(remaining) > 0 {
+		logger.Warnf("closing %d websocket connections not disconnected within drain timeout of %s", len(remaining), m.config.DrainTimeout)
+	}
+
+	// the read loop will exit on the closed connection and stop the connection
+	for _, c := range remaining {
+		_ = c.conn.Close()
+	}
+}
+
+// addStream tracks the stream to be ended when draining, returning false if the manager is already draining
+func (m *Manager) addStream(s *Stream) bool {
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	if m.draining {
+		return false
+	}
+
+	m.streams[s] = struct{}{}
+
+	return true
+}
+
+func (m *Manager) removeStream(s *Stream) {
+	m.m.Lock()
+	defer m.m.Unlock()
+	delete(m.streams, s)
+}
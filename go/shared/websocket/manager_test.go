@@ -0,0 +1,78 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/shapeshift/unchained/shared/metrics"
+)
+
+// waitForConnections fails the test if the manager does not have the number of connections expected before the timeout
+func waitForConnections(t *testing.T, manager *Manager, expected int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for manager.ConnectionCount() != expected {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d connections, got %d", expected, manager.ConnectionCount())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestDrainGoingAway(t *testing.T) {
+	manager := NewManager(metrics.NewPrometheus("test"))
+	manager.config.DrainTimeout = 5 * time.Second
+
+	client := dial(t, newTestServer(t, NewRegistry(), manager), nil)
+
+	waitForConnections(t, manager, 1)
+
+	done := make(chan struct{})
+	start := time.Now()
+
+	go func() {
+		manager.Drain()
+		close(done)
+	}()
+
+	// reading the going away close frame completes the close handshake, disconnecting the client
+	expectClose(t, client, websocket.CloseGoingAway)
+
+	select {
+	case <-done:
+	case <-time.After(manager.config.DrainTimeout):
+		t.Fatal("expected drain to complete once the client disconnected")
+	}
+
+	if elapsed := time.Since(start); elapsed >= manager.config.DrainTimeout {
+		t.Errorf("expected drain to complete before the drain timeout, took %s", elapsed)
+	}
+
+	if !manager.Draining() {
+		t.Error("expected manager to be draining")
+	}
+}
+
+func TestDrainTimeout(t *testing.T) {
+	manager := NewManager(metrics.NewPrometheus("test"))
+	manager.config.DrainTimeout = 200 * time.Millisecond
+
+	client := dial(t, newTestServer(t, NewRegistry(), manager), nil)
+
+	waitForConnections(t, manager, 1)
+
+	// the client never reads the going away close frame, so the connection is only closed once the drain timeout expires
+	start := time.Now()
+	manager.Drain()
+
+	if elapsed := time.Since(start); elapsed < manager.config.DrainTimeout {
+		t.Errorf("expected drain to wait for the drain timeout, took %s", elapsed)
+	}
+
+	waitForConnections(t, manager, 0)
+
+	// the going away close frame was still sent before the connection was closed
+	expectClose(t, client, websocket.CloseGoingAway)
+}
@@ -0,0 +1,93 @@
+package websocket
+
+import (
+	"encoding/json"
+
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+)
+
+// NATSBroker distributes published messages to all registries subscribed to the same subject on a nats server
+type NATSBroker struct {
+	conn    *nats.Conn
+	subject string
+	sub     *nats.Subscription
+}
+
+func NewNATSBroker(url string, subject string) (*NATSBroker, error) {
+	conn, err := nats.Connect(url,
+		nats.Name("unchained"),
+		nats.MaxReconnects(-1),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if err != nil {
+				logger.Errorf("disconnected from nats: %v", err)
+			}
+		}),
+		nats.ReconnectHandler(func(c *nats.Conn) {
+			logger.Infof("reconnected to nats: %s", c.ConnectedUrl())
+		}),
+	)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to connect to nats: %s", url)
+	}
+
+	b := &NATSBroker{
+		conn:    conn,
+		subject: subject,
+	}
+
+	return b, nil
+}
+
+func (b *NATSBroker) Publish(msg BrokerMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal broker message")
+	}
+
+	if err := b.conn.Publish(b.subject, data); err != nil {
+		return errors.Wrapf(err, "failed to publish to subject: %s", b.subject)
+	}
+
+	return nil
+}
+
+func (b *NATSBroker) Subscribe(handler BrokerHandlerFunc) error {
+	sub, err := b.conn.Subscribe(b.subject, func(m *nats.Msg) {
+		// keep data as raw json to avoid decoding and re-encoding for each client
+		msg := struct {
+			BrokerMessage
+			Data json.RawMessage `json:"data"`
+		}{}
+
+		if err := json.Unmarshal(m.Data, &msg); err != nil {
+			logger.Errorf("failed to unmarshal broker message: %v", err)
+			return
+		}
+
+		msg.BrokerMessage.Data = msg.Data
+
+		handler(msg.BrokerMessage)
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to subscribe to subject: %s", b.subject)
+	}
+
+	b.sub = sub
+
+	return nil
+}
+
+func (b *NATSBroker) Close() error {
+	if b.sub != nil {
+		if err := b.sub.Unsubscribe(); err != nil {
+			logger.Errorf("failed to unsubscribe from subject: %s: %v", b.subject, err)
+		}
+	}
+
+	if err := b.conn.Drain(); err != nil {
+		return errors.Wrap(err, "failed to drain nats connection")
+	}
+
+	return nil
+}
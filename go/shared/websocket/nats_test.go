@@ -0,0 +1,192 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+)
+
+// chanSubscriber forwards the messages sent to it to a channel and is safe for concurrent use
+type chanSubscriber struct {
+	msgs chan []byte
+}
+
+func newChanSubscriber() *chanSubscriber {
+	return &chanSubscriber{msgs: make(chan []byte, 10)}
+}
+
+func (s *chanSubscriber) Send(msg *Message) {
+	data, err := msg.JSON()
+	if err != nil {
+		return
+	}
+
+	select {
+	case s.msgs <- data:
+	default:
+	}
+}
+
+// receivedMessage is a message response as received by a client
+type receivedMessage struct {
+	Address        string          `json:"address"`
+	Data           json.RawMessage `json:"data"`
+	SubscriptionID string          `json:"subscriptionId"`
+	Topic          string          `json:"topic"`
+}
+
+// receive the next message sent to the subscriber
+func (s *chanSubscriber) receive(t *testing.T) receivedMessage {
+	t.Helper()
+
+	select {
+	case msg := <-s.msgs:
+		res := receivedMessage{}
+		if err := json.Unmarshal(msg, &res); err != nil {
+			t.Fatalf("failed to unmarshal message: %+v", err)
+		}
+		return res
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for message")
+	}
+
+	return receivedMessage{}
+}
+
+// txid of the tx in the message data
+func (m receivedMessage) txid(t *testing.T) string {
+	t.Helper()
+
+	tx := testTx{}
+	if err := json.Unmarshal(m.Data, &tx); err != nil {
+		t.Fatalf("failed to unmarshal tx: %+v", err)
+	}
+
+	return tx.TxID
+}
+
+// runNATSServer starts an embedded nats server for the duration of the test and returns its url
+func runNATSServer(t *testing.T) string {
+	t.Helper()
+
+	s, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: server.RANDOM_PORT, NoLog: true, NoSigs: true})
+	if err != nil {
+		t.Fatalf("failed to create nats server: %+v", err)
+	}
+
+	go s.Start()
+	t.Cleanup(s.Shutdown)
+
+	if !s.ReadyForConnections(5 * time.Second) {
+		t.Fatal("nats server not ready for connections")
+	}
+
+	return s.ClientURL()
+}
+
+func newNATSRegistry(t *testing.T, url string) *Registry {
+	t.Helper()
+
+	broker, err := NewNATSBroker(url, "test")
+	if err != nil {
+		t.Fatalf("failed to create nats broker: %+v", err)
+	}
+
+	r, err := NewRegistryWithBroker(broker)
+	if err != nil {
+		t.Fatalf("failed to create registry: %+v", err)
+	}
+	t.Cleanup(func() { _ = r.Close() })
+
+	// ensure the subscription is registered with the server before anything is published
+	if err := broker.conn.Flush(); err != nil {
+		t.Fatalf("failed to flush nats connection: %+v", err)
+	}
+
+	return r
+}
+
+type testTx struct {
+	TxID        string          `json:"txid"`
+	BlockHeight int             `json:"blockHeight"`
+	Messages    []testTxMessage `json:"messages"`
+}
+
+type testTxMessage struct {
+	From  string      `json:"from"`
+	To    string      `json:"to"`
+	Type  string      `json:"type"`
+	Value testTxValue `json:"value"`
+}
+
+type testTxValue struct {
+	Amount string `json:"amount"`
+	Denom  string `json:"denom"`
+}
+
+func newTestTx(txid string, from string, to string, amount string) testTx {
+	return testTx{
+		TxID:        txid,
+		BlockHeight: 10,
+		Messages:    []testTxMessage{{From: from, To: to, Type: "send", Value: testTxValue{Amount: amount, Denom: "uatom"}}},
+	}
+}
+
+func TestNATSBrokerDeliversAcrossRegistries(t *testing.T) {
+	url := runNATSServer(t)
+
+	publisher := newNATSRegistry(t, url)
+	receiver := newNATSRegistry(t, url)
+
+	receiver.BalanceHandler(func(ctx context.Context, addr string) (interface{}, error) {
+		return map[string]string{"address": addr, "amount": "150"}, nil
+	})
+
+	all := newChanSubscriber()
+	receiver.Subscribe("client", "all", []string{"addr"}, all, SubscribeOptions{})
+
+	filter := &Filter{Direction: DirectionIn, MinAmount: "100"}
+	if err := filter.Validate(); err != nil {
+		t.Fatalf("failed to validate filter: %+v", err)
+	}
+
+	filtered := newChanSubscriber()
+	receiver.Subscribe("client", "filtered", []string{"addr"}, filtered, SubscribeOptions{Filter: filter, Balances: true})
+
+	publisher.Publish([]string{"addr"}, newTestTx("out", "addr", "other", "150"))
+	publisher.Publish([]string{"addr"}, newTestTx("small", "other", "addr", "50"))
+	publisher.Publish([]string{"addr"}, newTestTx("in", "other", "addr", "150"))
+
+	for _, txid := range []string{"out", "small", "in"} {
+		msg := all.receive(t)
+		if msg.SubscriptionID != "all" || msg.txid(t) != txid {
+			t.Fatalf("expected %s for subscription all, got %s for subscription %s", txid, msg.Data, msg.SubscriptionID)
+		}
+	}
+
+	msg := filtered.receive(t)
+	if msg.SubscriptionID != "filtered" || msg.Topic != "" {
+		t.Fatalf("expected tx for subscription filtered, got %+v", msg)
+	}
+
+	if txid := msg.txid(t); txid != "in" {
+		t.Fatalf("expected only tx in to match filter, got %s", txid)
+	}
+
+	// balances are sent once on the next block, regardless of filters or how many txs were published
+	publisher.PublishTopic(TopicBlocks, map[string]int{"height": 11})
+
+	msg = filtered.receive(t)
+	if msg.SubscriptionID != "filtered" || msg.Topic != TopicBalances || msg.Address != "addr" {
+		t.Fatalf("expected balance for subscription filtered, got %+v", msg)
+	}
+
+	select {
+	case msg := <-filtered.msgs:
+		t.Fatalf("unexpected message: %s", msg)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -1,29 +1,116 @@
 package websocket
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
+	"hash/fnv"
 	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
 )
 
+// number of independently locked address partitions in the registry
+const registryShards = 256
+
+// Subscriber receives messages published to any of its subscriptions.
+// Send must not block as it is called while publishing to all subscribers.
+type Subscriber interface {
+	Send(msg *Message)
+}
+
+// SubscribeOptions configures how messages are delivered for an address subscription
+type SubscribeOptions struct {
+	// send a message once per subscription with all matched addresses instead of once per matched address
+	Dedupe bool
+	// only send messages matching the filter, if provided
+	Filter *Filter
+	// also send the updated balance of an address on the next block after any txs are published for it
+	Balances bool
+}
+
 type Registrar interface {
-	Subscribe(clientID string, subscriptionID string, addrs []string, msg chan<- []byte)
-	Unsubscribe(clientID string, subscriptionID string, addrs []string, msg chan<- []byte)
+	Subscribe(clientID string, subscriptionID string, addrs []string, subscriber Subscriber, opts SubscribeOptions)
+	Unsubscribe(clientID string, subscriptionID string, addrs []string, subscriber Subscriber) []string
 	Publish(addrs []string, data interface{})
+	SubscribeTopic(clientID string, subscriptionID string, topic string, subscriber Subscriber)
+	UnsubscribeTopic(clientID string, subscriptionID string, topic string, subscriber Subscriber)
+	PublishTopic(topic string, data interface{})
 }
 
-type Registry struct {
+// subscription is a subscriber registered for an address
+type subscription struct {
+	opts       SubscribeOptions
+	subscriber Subscriber
+}
+
+// shard holds the subscriptions for a partition of addresses and the addresses for a partition of IDs.
+// The clients lock is always acquired before the addresses lock and never held for more than one shard at a time.
+type shard struct {
+	// addresses to ID to subscription
+	addresses map[string]map[string]subscription
+	m         sync.RWMutex
 	// ID to addresses
-	clients map[string]map[string]struct{}
-	// addresses to ID to msgChan
-	addresses map[string]map[string]chan<- []byte
+	clients  map[string]map[string]struct{}
+	clientsM sync.Mutex
+}
+
+// Registry is safe for concurrent use. Addresses and IDs are partitioned across shards so that
+// subscribing and publishing for unrelated clients or addresses do not contend on the same lock.
+// Messages are published through the broker and delivered to the subscribers registered locally.
+type Registry struct {
+	broker Broker
+	shards [registryShards]*shard
+	// topic to ID to subscriber
+	topics  map[string]map[string]Subscriber
+	topicsM sync.RWMutex
+	// addresses with balances to be sent to balance subscriptions on the next block
+	pendingBalances map[string]struct{}
+	balanceHandler  BalanceHandlerFunc
+	balancesM       sync.Mutex
+	// cancelled on close to abort any in flight balance requests
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
+// NewRegistry creates a registry backed by an in memory broker
 func NewRegistry() *Registry {
-	return &Registry{
-		clients:   make(map[string]map[string]struct{}),
-		addresses: make(map[string]map[string]chan<- []byte),
+	r, _ := NewRegistryWithBroker(NewMemoryBroker())
+	return r
+}
+
+// NewRegistryWithBroker creates a registry that publishes and receives messages through the broker provided
+func NewRegistryWithBroker(broker Broker) (*Registry, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	r := &Registry{
+		broker:          broker,
+		topics:          make(map[string]map[string]Subscriber),
+		pendingBalances: make(map[string]struct{}),
+		ctx:             ctx,
+		cancel:          cancel,
 	}
+
+	for i := range r.shards {
+		r.shards[i] = &shard{
+			addresses: make(map[string]map[string]subscription),
+			clients:   make(map[string]map[string]struct{}),
+		}
+	}
+
+	if err := broker.Subscribe(r.deliver); err != nil {
+		cancel()
+		return nil, errors.Wrap(err, "failed to subscribe to broker")
+	}
+
+	return r, nil
+}
+
+// Close the broker backing the registry
+func (r *Registry) Close() error {
+	r.cancel()
+
+	return r.broker.Close()
 }
 
 func toID(clientID string, subscriptionID string) string {
@@ -31,82 +118,282 @@ func toID(clientID string, subscriptionID string) string {
 }
 
 func fromID(id string) (string, string) {
-	parts := strings.Split(id, ":")
-	return parts[0], parts[1]
+	clientID, subscriptionID, _ := strings.Cut(id, ":")
+	return clientID, subscriptionID
+}
+
+// shard returns the shard partitioning the address or ID provided
+func (r *Registry) shard(key string) *shard {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return r.shards[h.Sum32()%registryShards]
 }
 
-// Subscribe addresses to a client with a dedicated msgChan to push messages back to the client
-func (r *Registry) Subscribe(clientID string, subscriptionID string, addrs []string, msgChan chan<- []byte) {
+// Subscribe addresses to a client with a dedicated subscriber to push messages back to the client.
+// The options provided apply to all addresses of the subscription, including any previously subscribed.
+func (r *Registry) Subscribe(clientID string, subscriptionID string, addrs []string, subscriber Subscriber, opts SubscribeOptions) {
 	id := toID(clientID, subscriptionID)
+	c := r.shard(id)
 
-	if _, ok := r.clients[id]; !ok {
-		r.clients[id] = make(map[string]struct{})
+	c.clientsM.Lock()
+	defer c.clientsM.Unlock()
+
+	if _, ok := c.clients[id]; !ok {
+		c.clients[id] = make(map[string]struct{})
 	}
 
-	for _, addr := range addrs {
-		if _, ok := r.addresses[addr]; !ok {
-			r.addresses[addr] = make(map[string]chan<- []byte)
+	register := func(addr string) {
+		s := r.shard(addr)
+
+		s.m.Lock()
+		defer s.m.Unlock()
+
+		if _, ok := s.addresses[addr]; !ok {
+			s.addresses[addr] = make(map[string]subscription)
 		}
+		s.addresses[addr][id] = subscription{opts: opts, subscriber: subscriber}
+	}
+
+	for addr := range c.clients[id] {
+		register(addr)
+	}
 
-		r.clients[id][addr] = struct{}{}
-		r.addresses[addr][id] = msgChan
+	for _, addr := range addrs {
+		register(addr)
+		c.clients[id][addr] = struct{}{}
 	}
 }
 
-// Unsubscribe addresses from a client.
-// If no addresses are provided, unregister the client and all associated addresses.
-func (r *Registry) Unsubscribe(clientID string, subscriptionID string, addrs []string, msgChan chan<- []byte) {
+// Unsubscribe addresses from a client and return the addresses that were unsubscribed.
+// If no addresses are provided, unregister the client and all associated addresses and topics.
+func (r *Registry) Unsubscribe(clientID string, subscriptionID string, addrs []string, subscriber Subscriber) []string {
 	id := toID(clientID, subscriptionID)
 
-	if _, ok := r.clients[id]; !ok {
-		return
+	if len(addrs) == 0 {
+		r.unsubscribeTopics(id)
 	}
 
+	c := r.shard(id)
+
+	c.clientsM.Lock()
+	defer c.clientsM.Unlock()
+
+	if _, ok := c.clients[id]; !ok {
+		return nil
+	}
+
+	unsubscribed := []string{}
+
 	unregister := func(id string, addr string) {
+		if _, ok := c.clients[id][addr]; !ok {
+			return
+		}
+
 		// unregister address from client
-		delete(r.clients[id], addr)
+		delete(c.clients[id], addr)
+		unsubscribed = append(unsubscribed, addr)
+
+		s := r.shard(addr)
+
+		s.m.Lock()
+		defer s.m.Unlock()
 
 		// unregister client from address
-		delete(r.addresses[addr], id)
+		delete(s.addresses[addr], id)
 
 		// delete address from registry if no clients are registered anymore
-		if len(r.addresses[addr]) == 0 {
-			delete(r.addresses, addr)
+		if len(s.addresses[addr]) == 0 {
+			delete(s.addresses, addr)
 		}
 	}
 
 	if len(addrs) == 0 {
-		for addr := range r.clients[id] {
+		for addr := range c.clients[id] {
 			unregister(id, addr)
 		}
 
-		delete(r.clients, id)
+		delete(c.clients, id)
 	} else {
 		for _, addr := range addrs {
 			unregister(id, addr)
 		}
 	}
+
+	return unsubscribed
 }
 
 // Publish message to all clients subscribed to any of the addresses provided
 func (r *Registry) Publish(addrs []string, data interface{}) {
+	if err := r.broker.Publish(BrokerMessage{Addresses: addrs, Data: data}); err != nil {
+		logger.Errorf("failed to publish message: %+v", err)
+	}
+}
+
+// PublishTopic message to all clients subscribed to the topic provided
+func (r *Registry) PublishTopic(topic string, data interface{}) {
+	if err := r.broker.Publish(BrokerMessage{Data: data, Topic: topic}); err != nil {
+		logger.Errorf("failed to publish %s message: %+v", topic, err)
+	}
+}
+
+// deliver a message received from the broker to all locally registered subscribers
+func (r *Registry) deliver(msg BrokerMessage) {
+	if msg.Topic != "" {
+		r.deliverTopic(msg.Topic, msg.Data)
+		return
+	}
+
+	r.deliverAddresses(msg.Addresses, msg.Data)
+}
+
+func (r *Registry) deliverAddresses(addrs []string, data interface{}) {
+	// matched addresses for each deduplicated subscription, delivered once all addresses have been checked
+	deduped := make(map[string]*dedupedDelivery)
+
+	// data is only parsed if any subscription has a filter or balance subscription
+	var fd *filterData
+	parse := func() *filterData {
+		if fd == nil {
+			fd = parseFilterData(data)
+		}
+		return fd
+	}
+
+	// data is encoded at most once per encoding for all subscribers
+	payload := NewData(data)
+
+	matches := func(sub subscription, addr string) bool {
+		if sub.opts.Filter == nil {
+			return true
+		}
+
+		return sub.opts.Filter.match(addr, parse())
+	}
+
 	for _, addr := range addrs {
-		if _, ok := r.addresses[addr]; !ok {
-			continue
+		s := r.shard(addr)
+
+		// copy subscriptions so no lock is held while sending
+		s.m.RLock()
+		subscriptions := make(map[string]subscription, len(s.addresses[addr]))
+		for id, sub := range s.addresses[addr] {
+			subscriptions[id] = sub
 		}
+		s.m.RUnlock()
 
-		for id, msgChan := range r.addresses[addr] {
-			_, subscriptionID := fromID(id)
+		// balances are updated for any confirmed tx, regardless of filters
+		for _, sub := range subscriptions {
+			if sub.opts.Balances && parse().confirmed() {
+				r.trackBalance(addr)
+				break
+			}
+		}
 
-			logger.Debugf("Publish: subscriptionID: %s, address: %s", subscriptionID, addr)
+		for id, sub := range subscriptions {
+			if !matches(sub, addr) {
+				continue
+			}
 
-			msg, err := json.Marshal(MessageResponse{Address: addr, Data: data, SubscriptionID: subscriptionID})
-			if err != nil {
-				logger.Errorf("failed to marshal tx message: %v", err)
-				return
+			if sub.opts.Dedupe {
+				if _, ok := deduped[id]; !ok {
+					deduped[id] = &dedupedDelivery{data: payload, subscriber: sub.subscriber}
+				}
+				deduped[id].addrs = append(deduped[id].addrs, addr)
+				continue
 			}
 
-			msgChan <- msg
+			_, subscriptionID := fromID(id)
+
+			logger.Debugf("Publish: subscriptionID: %s, address: %s", subscriptionID, addr)
+
+			sub.subscriber.Send(NewDataMessage(MessageResponse{Address: addr, SubscriptionID: subscriptionID}, payload))
 		}
 	}
+
+	for id, d := range deduped {
+		_, subscriptionID := fromID(id)
+
+		logger.Debugf("Publish: subscriptionID: %s, addresses: %v", subscriptionID, d.addrs)
+
+		d.subscriber.Send(NewDataMessage(MessageResponse{Addresses: d.addrs, SubscriptionID: subscriptionID}, d.data))
+	}
+}
+
+type dedupedDelivery struct {
+	addrs      []string
+	data       *Data
+	subscriber Subscriber
+}
+
+// SubscribeTopic registers a client with a dedicated subscriber to receive all messages published to a topic
+func (r *Registry) SubscribeTopic(clientID string, subscriptionID string, topic string, subscriber Subscriber) {
+	id := toID(clientID, subscriptionID)
+
+	r.topicsM.Lock()
+	defer r.topicsM.Unlock()
+
+	if _, ok := r.topics[topic]; !ok {
+		r.topics[topic] = make(map[string]Subscriber)
+	}
+
+	r.topics[topic][id] = subscriber
+}
+
+// UnsubscribeTopic unregisters a client from a topic
+func (r *Registry) UnsubscribeTopic(clientID string, subscriptionID string, topic string, subscriber Subscriber) {
+	id := toID(clientID, subscriptionID)
+
+	r.topicsM.Lock()
+	defer r.topicsM.Unlock()
+
+	r.unsubscribeTopic(id, topic)
+}
+
+// unsubscribeTopics unregisters a client from all topics
+func (r *Registry) unsubscribeTopics(id string) {
+	r.topicsM.Lock()
+	defer r.topicsM.Unlock()
+
+	for topic := range r.topics {
+		r.unsubscribeTopic(id, topic)
+	}
+}
+
+// unsubscribeTopic expects the caller to hold the topics lock
+func (r *Registry) unsubscribeTopic(id string, topic string) {
+	if _, ok := r.topics[topic]; !ok {
+		return
+	}
+
+	delete(r.topics[topic], id)
+
+	// delete topic from registry if no clients are registered anymore
+	if len(r.topics[topic]) == 0 {
+		delete(r.topics, topic)
+	}
+}
+
+func (r *Registry) deliverTopic(topic string, data interface{}) {
+	// balances of addresses with txs published since the previous block are sent once per block
+	if topic == TopicBlocks {
+		go r.deliverBalances()
+	}
+
+	// copy subscribers so no lock is held while sending
+	r.topicsM.RLock()
+	subscribers := make(map[string]Subscriber, len(r.topics[topic]))
+	for id, subscriber := range r.topics[topic] {
+		subscribers[id] = subscriber
+	}
+	r.topicsM.RUnlock()
+
+	payload := NewData(data)
+
+	for id, subscriber := range subscribers {
+		_, subscriptionID := fromID(id)
+
+		logger.Debugf("PublishTopic: subscriptionID: %s, topic: %s", subscriptionID, topic)
+
+		subscriber.Send(NewDataMessage(MessageResponse{SubscriptionID: subscriptionID, Topic: topic}, payload))
+	}
 }
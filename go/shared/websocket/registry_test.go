@@ -0,0 +1,265 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingSubscriber counts the messages sent to it and is safe for concurrent use
+type countingSubscriber struct {
+	count atomic.Int64
+}
+
+func (s *countingSubscriber) Send(msg *Message) {
+	s.count.Add(1)
+}
+
+func TestRegistrySubscribeUnsubscribe(t *testing.T) {
+	registry := NewRegistry()
+	subscriber := &testSubscriber{}
+
+	registry.Subscribe("client", "sub", []string{"a", "b"}, subscriber, SubscribeOptions{})
+	registry.Publish([]string{"a", "b", "c"}, "tx")
+
+	if len(subscriber.msgs) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(subscriber.msgs))
+	}
+
+	unsubscribed := registry.Unsubscribe("client", "sub", []string{"a", "c"}, subscriber)
+	if len(unsubscribed) != 1 || unsubscribed[0] != "a" {
+		t.Fatalf("expected only a to be unsubscribed, got %v", unsubscribed)
+	}
+
+	subscriber.msgs = nil
+	registry.Publish([]string{"a", "b"}, "tx")
+
+	if len(subscriber.msgs) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(subscriber.msgs))
+	}
+
+	msg := MessageResponse{}
+	if err := json.Unmarshal(subscriber.msgs[0], &msg); err != nil {
+		t.Fatalf("failed to unmarshal message: %+v", err)
+	}
+
+	if msg.Address != "b" || msg.SubscriptionID != "sub" {
+		t.Fatalf("unexpected message: %s", subscriber.msgs[0])
+	}
+
+	unsubscribed = registry.Unsubscribe("client", "sub", nil, subscriber)
+	if len(unsubscribed) != 1 || unsubscribed[0] != "b" {
+		t.Fatalf("expected only b to be unsubscribed, got %v", unsubscribed)
+	}
+
+	assertRegistryEmpty(t, registry)
+}
+
+// TestRegistryConcurrent subscribes, unsubscribes and publishes overlapping addresses from many clients at once
+// and is intended to be run with the race detector enabled
+func TestRegistryConcurrent(t *testing.T) {
+	const (
+		clients    = 16
+		iterations = 50
+		addresses  = 16
+	)
+
+	registry := NewRegistry()
+
+	addrs := make([]string, addresses)
+	for i := range addrs {
+		addrs[i] = fmt.Sprintf("addr%d", i)
+	}
+
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < clients; i++ {
+		clientID := fmt.Sprintf("client%d", i)
+		subscriber := &countingSubscriber{}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < iterations; j++ {
+				subscriptionID := fmt.Sprintf("sub%d", j%4)
+				subscribed := []string{addrs[j%addresses], addrs[(j+i)%addresses]}
+
+				registry.Subscribe(clientID, subscriptionID, subscribed, subscriber, SubscribeOptions{Dedupe: j%2 == 0})
+				registry.SubscribeTopic(clientID, subscriptionID, TopicBlocks, subscriber)
+				registry.Publish(subscribed, "tx")
+				registry.PublishTopic(TopicBlocks, "block")
+
+				if j%3 == 0 {
+					registry.Unsubscribe(clientID, subscriptionID, subscribed[:1], subscriber)
+				} else {
+					registry.Unsubscribe(clientID, subscriptionID, nil, subscriber)
+				}
+			}
+
+			for j := 0; j < 4; j++ {
+				registry.Unsubscribe(clientID, fmt.Sprintf("sub%d", j), nil, subscriber)
+			}
+		}()
+	}
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < iterations; j++ {
+				registry.Publish(addrs, "tx")
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	assertRegistryEmpty(t, registry)
+}
+
+// reentrantSubscriber unsubscribes itself when sent a message, which deadlocks if any registry lock is held while sending
+type reentrantSubscriber struct {
+	registry *Registry
+	sent     chan struct{}
+}
+
+func (s *reentrantSubscriber) Send(msg *Message) {
+	if msg.response.Topic == TopicBlocks {
+		s.registry.UnsubscribeTopic("client", "blocks", TopicBlocks, s)
+	} else {
+		s.registry.Unsubscribe("client", "sub", nil, s)
+	}
+	s.sent <- struct{}{}
+}
+
+func TestRegistrySendWithoutLock(t *testing.T) {
+	registry := NewRegistry()
+	subscriber := &reentrantSubscriber{registry: registry, sent: make(chan struct{}, 2)}
+
+	registry.Subscribe("client", "sub", []string{"a"}, subscriber, SubscribeOptions{})
+	registry.SubscribeTopic("client", "blocks", TopicBlocks, subscriber)
+
+	done := make(chan struct{})
+	go func() {
+		registry.Publish([]string{"a"}, "tx")
+		registry.PublishTopic(TopicBlocks, "block")
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected publish not to hold any lock while sending")
+	}
+
+	if n := len(subscriber.sent); n != 2 {
+		t.Fatalf("expected 2 messages, got %d", n)
+	}
+
+	assertRegistryEmpty(t, registry)
+}
+
+func TestRegistryDedupeAcrossShards(t *testing.T) {
+	registry := NewRegistry()
+	subscriber := &testSubscriber{}
+
+	// find addresses partitioned into different shards
+	addrs := []string{"addr0"}
+	for i := 1; len(addrs) < 2; i++ {
+		if addr := fmt.Sprintf("addr%d", i); registry.shard(addr) != registry.shard(addrs[0]) {
+			addrs = append(addrs, addr)
+		}
+	}
+
+	registry.Subscribe("client", "sub", addrs, subscriber, SubscribeOptions{Dedupe: true})
+	registry.Publish(addrs, "tx")
+
+	if len(subscriber.msgs) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(subscriber.msgs))
+	}
+
+	msg := MessageResponse{}
+	if err := json.Unmarshal(subscriber.msgs[0], &msg); err != nil {
+		t.Fatalf("failed to unmarshal message: %+v", err)
+	}
+
+	if len(msg.Addresses) != 2 || msg.Addresses[0] != addrs[0] || msg.Addresses[1] != addrs[1] {
+		t.Fatalf("expected addresses %v, got %v", addrs, msg.Addresses)
+	}
+
+	registry.Unsubscribe("client", "sub", nil, subscriber)
+
+	assertRegistryEmpty(t, registry)
+}
+
+func assertRegistryEmpty(t *testing.T, registry *Registry) {
+	t.Helper()
+
+	for i, s := range registry.shards {
+		s.clientsM.Lock()
+		s.m.RLock()
+		if len(s.addresses) != 0 || len(s.clients) != 0 {
+			t.Errorf("expected shard %d to be empty, got addresses: %v, clients: %v", i, s.addresses, s.clients)
+		}
+		s.m.RUnlock()
+		s.clientsM.Unlock()
+	}
+
+	registry.topicsM.RLock()
+	defer registry.topicsM.RUnlock()
+
+	if len(registry.topics) != 0 {
+		t.Errorf("expected no topics, got %v", registry.topics)
+	}
+}
+
+func TestRegistryBalancesOncePerBlock(t *testing.T) {
+	registry := NewRegistry()
+
+	var calls atomic.Int64
+	registry.BalanceHandler(func(ctx context.Context, addr string) (interface{}, error) {
+		calls.Add(1)
+		return map[string]string{"address": addr, "amount": "150"}, nil
+	})
+
+	subscriber := newChanSubscriber()
+	registry.Subscribe("client", "sub", []string{"addr"}, subscriber, SubscribeOptions{Balances: true})
+
+	for _, txid := range []string{"a", "b", "c"} {
+		registry.Publish([]string{"addr"}, newTestTx(txid, "other", "addr", "150"))
+
+		if msg := subscriber.receive(t); msg.txid(t) != txid {
+			t.Fatalf("expected tx %s, got %s", txid, msg.Data)
+		}
+	}
+
+	if n := calls.Load(); n != 0 {
+		t.Fatalf("expected no balance requests before the next block, got %d", n)
+	}
+
+	registry.PublishTopic(TopicBlocks, map[string]int{"height": 11})
+
+	msg := subscriber.receive(t)
+	if msg.Topic != TopicBalances || msg.Address != "addr" || msg.SubscriptionID != "sub" {
+		t.Fatalf("expected balance for addr, got %+v", msg)
+	}
+
+	// no balance is sent for a block without any new txs for the address
+	registry.PublishTopic(TopicBlocks, map[string]int{"height": 12})
+
+	select {
+	case msg := <-subscriber.msgs:
+		t.Fatalf("unexpected message: %s", msg)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("expected 1 balance request, got %d", n)
+	}
+}
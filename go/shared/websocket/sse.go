@@ -0,0 +1,236 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/shapeshift/unchained/shared/metrics"
+)
+
+const (
+	streamSubscriptionID = "stream"
+	streamEventTx        = "tx"
+	streamEventError     = "error"
+)
+
+// Stream delivers the messages published for an address to a client as server-sent events
+type Stream struct {
+	clientID      string
+	done          chan struct{}
+	doneOnce      sync.Once
+	handler       Registrar
+	manager       *Manager
+	queue         chan *Message
+	replayHandler ReplayHandlerFunc
+}
+
+func NewStream(handler Registrar, manager *Manager) *Stream {
+	return &Stream{
+		clientID: uuid.NewString(),
+		done:     make(chan struct{}),
+		handler:  handler,
+		manager:  manager,
+		queue:    make(chan *Message, manager.config.QueueSize),
+	}
+}
+
+// ReplayHandler sets the handler used to backfill any missed messages when a stream is resumed
+func (s *Stream) ReplayHandler(fn ReplayHandlerFunc) {
+	s.replayHandler = fn
+}
+
+// Send a message to the client without blocking by adding it to the bounded outbound queue.
+// if the queue is full, the configured overflow policy is applied.
+func (s *Stream) Send(msg *Message) {
+	select {
+	case s.queue <- msg:
+		return
+	default:
+	}
+
+	switch s.manager.config.QueueOverflowPolicy {
+	case OverflowDisconnect:
+		s.manager.prometheus.Metrics.WebsocketDroppedMessages.With(metrics.Labels{"policy": string(OverflowDisconnect)}).Inc()
+		s.doneOnce.Do(func() {
+			logger.Warnf("queue full: disconnecting stream clientID: %s", s.clientID)
+			close(s.done)
+		})
+	default:
+		select {
+		case <-s.queue:
+		default:
+		}
+
+		select {
+		case s.queue <- msg:
+		default:
+		}
+
+		s.manager.prometheus.Metrics.WebsocketDroppedMessages.With(metrics.Labels{"policy": string(OverflowDropOldest)}).Inc()
+	}
+}
+
+// Serve the stream for the address until the client disconnects.
+// If a Last-Event-ID header (or lastEventId query parameter) is provided, any messages published after the last event are replayed before switching over to live delivery.
+func (s *Stream) Serve(w http.ResponseWriter, r *http.Request, addr string) {
+	if !s.manager.addStream(s) {
+		http.Error(w, goingAwayReason, http.StatusServiceUnavailable)
+		return
+	}
+	defer s.manager.removeStream(s)
+
+	rc := http.NewResponseController(w)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// disable response buffering by nginx proxies
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if err := rc.Flush(); err != nil {
+		logger.Errorf("failed to flush stream: %+v", err)
+		return
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("lastEventId")
+	}
+
+	logger.Debugf("NewStream: clientID: %s, address: %s, lastEventID: %s", s.clientID, addr, lastEventID)
+
+	// buffer live messages while replaying any missed messages
+	buffer := &replaySubscriber{clientID: s.clientID, target: s, overflow: s.stop}
+	if lastEventID == "" || s.replayHandler == nil {
+		buffer.live = true
+	}
+
+	s.handler.Subscribe(s.clientID, streamSubscriptionID, []string{addr}, buffer, SubscribeOptions{})
+	defer s.handler.Unsubscribe(s.clientID, streamSubscriptionID, nil, buffer)
+
+	if !buffer.live {
+		if err := s.replay(r.Context(), rc, w, addr, lastEventID); err != nil {
+			logger.Errorf("failed to write stream event: %+v", err)
+			return
+		}
+
+		buffer.flush()
+	}
+
+	ticker := time.NewTicker(pingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			logger.Debugf("Stop: stream clientID: %s", s.clientID)
+			return
+		case <-s.done:
+			return
+		case <-ticker.C:
+			// comment line to keep the connection alive through any idle timeouts
+			if err := s.write(rc, w, ": ping\n\n"); err != nil {
+				logger.Errorf("failed to write stream ping: %+v", err)
+				return
+			}
+		case msg := <-s.queue:
+			data, err := msg.JSON()
+			if err != nil {
+				logger.Errorf("failed to encode stream event: %+v", err)
+				continue
+			}
+
+			if err := s.writeEvent(rc, w, streamEventTx, data); err != nil {
+				logger.Errorf("failed to write stream event: %+v", err)
+				return
+			}
+		}
+	}
+}
+
+// stop the stream, ending the response so the client reconnects
+func (s *Stream) stop() {
+	s.doneOnce.Do(func() { close(s.done) })
+}
+
+func (s *Stream) replay(ctx context.Context, rc *http.ResponseController, w http.ResponseWriter, addr string, lastEventID string) error {
+	data, err := s.replayHandler(ctx, addr, 0, lastEventID)
+	if ctx.Err() != nil {
+		// client disconnected during the replay, the stream is ended once the replay returns
+		return nil
+	}
+
+	if err != nil {
+		logger.Errorf("failed to replay messages for address: %s: %+v", addr, err)
+
+		msg, err := json.Marshal(ErrorResponse{
+			Code:           ErrorCodeReplayFailed,
+			Message:        fmt.Sprintf("failed to replay messages for address: %s", addr),
+			SubscriptionID: streamSubscriptionID,
+			Type:           "error",
+		})
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal error response")
+		}
+
+		return s.writeEvent(rc, w, streamEventError, msg)
+	}
+
+	logger.Debugf("Replay: stream clientID: %s, address: %s, messages: %d", s.clientID, addr, len(data))
+
+	for _, d := range data {
+		msg, err := json.Marshal(MessageResponse{Address: addr, Data: d, SubscriptionID: streamSubscriptionID})
+		if err != nil {
+			logger.Errorf("failed to marshal replay message: %v", err)
+			continue
+		}
+
+		if err := s.writeEvent(rc, w, streamEventTx, msg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeEvent writes the message as an event using the txid of the message data, if any, as the event id for resuming.
+// Pending txs are written without an event id as they can not be replayed from, leaving the client to resume from the last confirmed tx.
+func (s *Stream) writeEvent(rc *http.ResponseController, w http.ResponseWriter, event string, msg []byte) error {
+	payload := struct {
+		Data struct {
+			TxID        string `json:"txid"`
+			BlockHeight *int   `json:"blockHeight"`
+		} `json:"data"`
+	}{}
+
+	// message data is not required to be a tx
+	_ = json.Unmarshal(msg, &payload)
+
+	pending := payload.Data.BlockHeight != nil && *payload.Data.BlockHeight < 0
+
+	if payload.Data.TxID != "" && !pending {
+		return s.write(rc, w, fmt.Sprintf("id: %s\nevent: %s\ndata: %s\n\n", payload.Data.TxID, event, msg))
+	}
+
+	return s.write(rc, w, fmt.Sprintf("event: %s\ndata: %s\n\n", event, msg))
+}
+
+func (s *Stream) write(rc *http.ResponseController, w http.ResponseWriter, data string) error {
+	// extend the server write timeout for the long lived response
+	if err := rc.SetWriteDeadline(time.Now().Add(writeWait)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return errors.Wrap(err, "failed to set write deadline")
+	}
+
+	if _, err := w.Write([]byte(data)); err != nil {
+		return errors.Wrap(err, "failed to write")
+	}
+
+	return errors.Wrap(rc.Flush(), "failed to flush")
+}
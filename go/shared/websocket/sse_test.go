@@ -0,0 +1,121 @@
+package websocket
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/shapeshift/unchained/shared/api"
+	"github.com/shapeshift/unchained/shared/metrics"
+)
+
+func TestStreamThroughMiddleware(t *testing.T) {
+	prometheus := metrics.NewPrometheus("test")
+	registry := NewRegistry()
+	manager := NewManager(prometheus)
+
+	auth, err := api.NewAuth(nil, prometheus)
+	if err != nil {
+		t.Fatalf("failed to create auth: %+v", err)
+	}
+
+	r := mux.NewRouter()
+	r.Use(api.Scheme, api.Tracing, api.Logger(prometheus), auth.Middleware)
+	r.HandleFunc("/api/v1/account/{pubkey}/stream", func(w http.ResponseWriter, r *http.Request) {
+		NewStream(registry, manager).Serve(w, r, mux.Vars(r)["pubkey"])
+	}).Methods("GET")
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "/api/v1/account/addr1/stream")
+	if err != nil {
+		t.Fatalf("failed to open stream: %+v", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status: %d", res.StatusCode)
+	}
+
+	if contentType := res.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Fatalf("unexpected content type: %s", contentType)
+	}
+
+	events := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(res.Body)
+		for scanner.Scan() {
+			if line := scanner.Text(); strings.HasPrefix(line, "data: ") {
+				events <- line
+			}
+		}
+		close(events)
+	}()
+
+	// the subscription is registered after the response headers are flushed, so publish until the event is received
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	timeout := time.After(5 * time.Second)
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				t.Fatal("stream closed before an event was received")
+			}
+
+			if !strings.Contains(event, `"txid":"txid1"`) {
+				t.Fatalf("unexpected event: %s", event)
+			}
+
+			return
+		case <-ticker.C:
+			registry.Publish([]string{"addr1"}, map[string]string{"txid": "txid1"})
+		case <-timeout:
+			t.Fatal("timed out waiting for stream event")
+		}
+	}
+}
+
+func TestWriteEventID(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  string
+		want string
+	}{
+		{
+			name: "confirmed tx",
+			msg:  `{"data":{"txid":"abc","blockHeight":10}}`,
+			want: "id: abc\nevent: tx\ndata: {\"data\":{\"txid\":\"abc\",\"blockHeight\":10}}\n\n",
+		},
+		{
+			name: "pending tx",
+			msg:  `{"data":{"txid":"abc","blockHeight":-1}}`,
+			want: "event: tx\ndata: {\"data\":{\"txid\":\"abc\",\"blockHeight\":-1}}\n\n",
+		},
+		{
+			name: "not a tx",
+			msg:  `{"data":{"amount":"1"}}`,
+			want: "event: tx\ndata: {\"data\":{\"amount\":\"1\"}}\n\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			if err := (&Stream{}).writeEvent(http.NewResponseController(w), w, streamEventTx, []byte(tt.msg)); err != nil {
+				t.Fatalf("failed to write event: %+v", err)
+			}
+
+			if got := w.Body.String(); got != tt.want {
+				t.Fatalf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
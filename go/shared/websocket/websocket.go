@@ -1,70 +1,154 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"maps"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"github.com/shapeshift/unchained/shared/log"
+	"github.com/shapeshift/unchained/shared/metrics"
+	"golang.org/x/time/rate"
 )
 
 const (
 	writeWait      = 15 * time.Second
 	readWait       = 15 * time.Second
 	pingPeriod     = (readWait * 9) / 10
-	maxMessageSize = 1024
+	maxMessageSize = 8192 // fits a subscribe request with the default max addresses per subscription
+	replayBuffer   = 1024
+	// reason sent with the going away close frame when draining connections on shutdown
+	goingAwayReason = "server shutting down, reconnect"
+)
+
+const (
+	TopicTxs    = "txs"
+	TopicBlocks = "blocks"
+	// updated balances of addresses subscribed to with balances enabled
+	TopicBalances = "balances"
 )
 
 var logger = log.WithoutFields()
 
 type RequestPayload struct {
 	Data struct {
-		Topic     string   `json:"topic"`
-		Addresses []string `json:"addresses"`
+		Topic      string   `json:"topic"`
+		Addresses  []string `json:"addresses"`
+		FromHeight int      `json:"fromHeight,omitempty"`
+		FromTxID   string   `json:"fromTxid,omitempty"`
+		Dedupe     bool     `json:"dedupe,omitempty"`
+		Filter     *Filter  `json:"filter,omitempty"`
+		Balances   bool     `json:"balances,omitempty"`
 	} `json:"data"`
 	Method         string `json:"method"`
 	SubscriptionID string `json:"subscriptionId"`
 }
 
+// ErrorCode is a machine readable code identifying the reason for an error response
+type ErrorCode string
+
+const (
+	ErrorCodeInvalidMessage       ErrorCode = "INVALID_MESSAGE"
+	ErrorCodeUnknownMethod        ErrorCode = "UNKNOWN_METHOD"
+	ErrorCodeUnknownTopic         ErrorCode = "UNKNOWN_TOPIC"
+	ErrorCodeSubscriptionID       ErrorCode = "SUBSCRIPTION_ID_REQUIRED"
+	ErrorCodeAddressesRequired    ErrorCode = "ADDRESSES_REQUIRED"
+	ErrorCodeInvalidAddress       ErrorCode = "INVALID_ADDRESS"
+	ErrorCodeTooManyAddresses     ErrorCode = "TOO_MANY_ADDRESSES"
+	ErrorCodeTooManySubscriptions ErrorCode = "TOO_MANY_SUBSCRIPTIONS"
+	ErrorCodeRateLimited          ErrorCode = "RATE_LIMITED"
+	ErrorCodeReplayNotSupported   ErrorCode = "REPLAY_NOT_SUPPORTED"
+	ErrorCodeReplayFailed         ErrorCode = "REPLAY_FAILED"
+	ErrorCodeShuttingDown         ErrorCode = "SHUTTING_DOWN"
+	ErrorCodeInvalidFilter        ErrorCode = "INVALID_FILTER"
+	ErrorCodeTopicMismatch        ErrorCode = "SUBSCRIPTION_TOPIC_MISMATCH"
+)
+
 type ErrorResponse struct {
-	Message        string `json:"message"`
-	SubscriptionID string `json:"subscriptionId"`
-	Type           string `json:"type"`
+	Code           ErrorCode `json:"code"`
+	Message        string    `json:"message"`
+	SubscriptionID string    `json:"subscriptionId"`
+	Type           string    `json:"type"`
+}
+
+// SubscriptionResponse acknowledges a subscribe or unsubscribe request
+type SubscriptionResponse struct {
+	Addresses      []string `json:"addresses,omitempty"`
+	SubscriptionID string   `json:"subscriptionId"`
+	Topic          string   `json:"topic"`
+	Type           string   `json:"type"`
 }
 
 type MessageResponse struct {
-	Address        string      `json:"address"`
+	Address        string      `json:"address,omitempty"`
+	Addresses      []string    `json:"addresses,omitempty"`
 	Data           interface{} `json:"data"`
 	SubscriptionID string      `json:"subscriptionId"`
+	Topic          string      `json:"topic,omitempty"`
 }
 
+// AddressValidatorFunc returns true if the address is valid for the coinstack
+type AddressValidatorFunc = func(addr string) bool
+
+// ReplayHandlerFunc returns the message data for an address published at or after fromHeight, or after fromTxID, in the order it was published.
+// ctx is cancelled if the subscription is unsubscribed or the connection is stopped before the replay completes.
+type ReplayHandlerFunc = func(ctx context.Context, addr string, fromHeight int, fromTxID string) ([]interface{}, error)
+
 // Connection represents a single websocket connection on the unchained api server
 type Connection struct {
-	clientID        string
-	conn            *websocket.Conn
-	doneChan        chan interface{}
-	handler         Registrar
-	manager         *Manager
-	msgChan         chan []byte
-	subscriptionIDs map[string]struct{}
-	ticker          *time.Ticker
-	m               sync.Mutex
+	addressValidator AddressValidatorFunc
+	cancel           context.CancelFunc
+	clientID         string
+	conn             *websocket.Conn
+	ctx              context.Context
+	doneChan         chan interface{}
+	encoding         Encoding
+	handler          Registrar
+	manager          *Manager
+	overflowed       atomic.Bool
+	queue            chan *Message
+	queueClosed      bool
+	replayHandler    ReplayHandlerFunc
+	stopOnce         sync.Once
+	addressCount     int
+	// subscribe requests allowed per second, with bursts of up to the same number
+	rateLimiter *rate.Limiter
+	// subscription ID to the cancel func of any replay started by the subscription
+	replays map[string]context.CancelFunc
+	// subscription ID to topic for subscriptions to a topic other than txs
+	topics map[string]string
+	// subscription ID to subscribed addresses
+	subscriptions map[string]map[string]struct{}
+	ticker        *time.Ticker
+	m             sync.Mutex
+	// guards queue against sends after close. senders hold the read lock.
+	qm sync.RWMutex
 }
 
 // NewConnection defines the connection and registers it with the manager
 func NewConnection(conn *websocket.Conn, handler Registrar, manager *Manager) *Connection {
+	ctx, cancel := context.WithCancel(context.Background())
+
 	c := &Connection{
-		clientID:        uuid.NewString(),
-		conn:            conn,
-		doneChan:        make(chan interface{}),
-		handler:         handler,
-		manager:         manager,
-		msgChan:         make(chan []byte),
-		subscriptionIDs: make(map[string]struct{}),
+		cancel:        cancel,
+		clientID:      uuid.NewString(),
+		conn:          conn,
+		ctx:           ctx,
+		doneChan:      make(chan interface{}),
+		encoding:      EncodingFromSubprotocol(conn.Subprotocol()),
+		handler:       handler,
+		manager:       manager,
+		queue:         make(chan *Message, manager.config.QueueSize),
+		rateLimiter:   rate.NewLimiter(rate.Limit(manager.config.SubscribeRate), manager.config.SubscribeRate),
+		replays:       make(map[string]context.CancelFunc),
+		subscriptions: make(map[string]map[string]struct{}),
+		topics:        make(map[string]string),
 	}
 
 	c.manager.register <- c
@@ -74,6 +158,16 @@ func NewConnection(conn *websocket.Conn, handler Registrar, manager *Manager) *C
 	return c
 }
 
+// AddressValidator sets the validator used to reject subscriptions for invalid addresses
+func (c *Connection) AddressValidator(fn AddressValidatorFunc) {
+	c.addressValidator = fn
+}
+
+// ReplayHandler sets the handler used to backfill any missed messages when a subscription is resumed
+func (c *Connection) ReplayHandler(fn ReplayHandlerFunc) {
+	c.replayHandler = fn
+}
+
 // Start the connection heartbeat and associated ping/pong handlers and
 // begin listening for any messages and handling them appropriately.
 func (c *Connection) Start() {
@@ -129,17 +223,21 @@ func (c *Connection) Start() {
 }
 
 // Stop the websocket connection by unsubscribing all client subscriptions and unregistering the client from the manager.
+// Stop is safe to call more than once.
 func (c *Connection) Stop() {
-	for subscriptionID := range c.subscriptionIDs {
-		c.handler.Unsubscribe(c.clientID, subscriptionID, nil, c.msgChan)
-	}
+	c.stopOnce.Do(func() {
+		// cancel any replays in progress
+		c.cancel()
+
+		for subscriptionID := range c.subscriptions {
+			c.handler.Unsubscribe(c.clientID, subscriptionID, nil, c)
+			c.removeSubscription(subscriptionID, nil)
+		}
 
-	// ensure connection has not already been unregistered before unregistering.
-	if _, ok := c.manager.connections[c]; ok {
 		c.manager.unregister <- c
-	}
 
-	logger.Debugf("Stop: clientID: %s (total: %d)", c.clientID, c.manager.ConnectionCount())
+		logger.Debugf("Stop: clientID: %s (total: %d)", c.clientID, c.manager.ConnectionCount())
+	})
 }
 
 func (c *Connection) cleanup() {
@@ -147,7 +245,34 @@ func (c *Connection) cleanup() {
 	c.ticker.Stop()
 	_ = c.send(websocket.CloseMessage, []byte{})
 	_ = c.conn.Close()
-	close(c.msgChan)
+
+	c.qm.Lock()
+	defer c.qm.Unlock()
+	c.queueClosed = true
+	close(c.queue)
+}
+
+// close the websocket connection with the close code and reason provided.
+// the read loop will exit on the closed connection and stop the connection.
+func (c *Connection) close(code int, reason string) {
+	if err := c.conn.SetWriteDeadline(time.Now().Add(writeWait)); err != nil {
+		logger.Errorf("failed to set write deadline: %+v", err)
+	}
+	if err := c.send(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason)); err != nil {
+		logger.Errorf("failed to write close message: %+v", err)
+	}
+	_ = c.conn.Close()
+}
+
+// goAway sends a going away close frame asking the client to reconnect.
+// the connection is left open for the client to complete the close handshake, at which point the read loop will exit and stop the connection.
+func (c *Connection) goAway() {
+	if err := c.conn.SetWriteDeadline(time.Now().Add(writeWait)); err != nil {
+		logger.Errorf("failed to set write deadline: %+v", err)
+	}
+	if err := c.send(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, goingAwayReason)); err != nil {
+		logger.Errorf("failed to write close message: %+v", err)
+	}
 }
 
 func (c *Connection) send(messageType int, data []byte) error {
@@ -167,51 +292,527 @@ func (c *Connection) read() {
 
 		r := &RequestPayload{}
 		if err := json.Unmarshal(msg, r); err != nil {
-			c.writeError(fmt.Sprintf("failed to parse message: %v", err), "")
+			c.writeError(ErrorCodeInvalidMessage, fmt.Sprintf("failed to parse message: %v", err), "")
 			continue
 		}
 
 		switch r.Method {
 		case "ping":
-			// browsers side pong message
+			// browsers side pong message, always sent as text regardless of the negotiated encoding as it is a heartbeat rather than a message
 			if err := c.send(websocket.TextMessage, []byte("pong")); err != nil {
 				logger.Errorf("failed to write pong message: %+v", err)
 			}
 		case "subscribe":
-			logger.Debugf("Subscribe: clientID: %s, subscriptionID: %s, addresses: %v", c.clientID, r.SubscriptionID, r.Data.Addresses)
-			c.subscriptionIDs[r.SubscriptionID] = struct{}{}
-			c.handler.Subscribe(c.clientID, r.SubscriptionID, r.Data.Addresses, c.msgChan)
+			logger.Debugf("Subscribe: clientID: %s, subscriptionID: %s, topic: %s, addresses: %v", c.clientID, r.SubscriptionID, r.Data.Topic, r.Data.Addresses)
+			c.handleSubscribe(r)
 		case "unsubscribe":
-			logger.Debugf("Unsubscribe: clientID: %s, subscriptionID: %s, addresses: %v", c.clientID, r.SubscriptionID, r.Data.Addresses)
-			if r.SubscriptionID != "" {
-				delete(c.subscriptionIDs, r.SubscriptionID)
-				c.handler.Unsubscribe(c.clientID, r.SubscriptionID, r.Data.Addresses, c.msgChan)
-			} else {
-				subscriptionIDs := maps.Keys(c.subscriptionIDs)
-				for subscriptionID := range subscriptionIDs {
-					delete(c.subscriptionIDs, subscriptionID)
-					c.handler.Unsubscribe(c.clientID, subscriptionID, r.Data.Addresses, c.msgChan)
+			logger.Debugf("Unsubscribe: clientID: %s, subscriptionID: %s, topic: %s, addresses: %v", c.clientID, r.SubscriptionID, r.Data.Topic, r.Data.Addresses)
+			c.handleUnsubscribe(r)
+		default:
+			c.writeError(ErrorCodeUnknownMethod, fmt.Sprintf("%s method not implemented", r.Method), r.SubscriptionID)
+		}
+	}
+}
+
+func (c *Connection) handleSubscribe(r *RequestPayload) {
+	prometheus := c.manager.prometheus.Metrics
+
+	if c.manager.Draining() {
+		c.writeError(ErrorCodeShuttingDown, goingAwayReason, r.SubscriptionID)
+		return
+	}
+
+	if !c.rateLimiter.Allow() {
+		prometheus.WebsocketLimitExceeded.With(metrics.Labels{"limit": "subscribe_rate"}).Inc()
+		c.writeError(ErrorCodeRateLimited, fmt.Sprintf("too many subscribe requests (max: %d/s)", c.manager.config.SubscribeRate), r.SubscriptionID)
+		return
+	}
+
+	if r.SubscriptionID == "" {
+		c.writeError(ErrorCodeSubscriptionID, "subscriptionId required", r.SubscriptionID)
+		return
+	}
+
+	// reusing a subscription id for another topic would leave the previous subscription registered, so it must be unsubscribed first
+	if topic, ok := c.subscriptionTopic(r.SubscriptionID); ok && topic != requestTopic(r.Data.Topic) {
+		c.writeError(ErrorCodeTopicMismatch, fmt.Sprintf("subscription %s is subscribed to topic: %s (unsubscribe before reusing the subscription id)", r.SubscriptionID, topic), r.SubscriptionID)
+		return
+	}
+
+	if _, ok := c.subscriptions[r.SubscriptionID]; !ok && len(c.subscriptions) >= c.manager.config.MaxSubscriptions {
+		prometheus.WebsocketLimitExceeded.With(metrics.Labels{"limit": "subscriptions"}).Inc()
+		c.writeError(ErrorCodeTooManySubscriptions, fmt.Sprintf("too many subscriptions (max: %d)", c.manager.config.MaxSubscriptions), r.SubscriptionID)
+		return
+	}
+
+	switch r.Data.Topic {
+	case "", TopicTxs:
+		addrs, ok := c.validateAddresses(r.SubscriptionID, r.Data.Addresses)
+		if !ok {
+			return
+		}
+
+		if r.Data.Filter != nil {
+			if err := r.Data.Filter.Validate(); err != nil {
+				c.writeError(ErrorCodeInvalidFilter, err.Error(), r.SubscriptionID)
+				return
+			}
+		}
+
+		opts := SubscribeOptions{Dedupe: r.Data.Dedupe, Filter: r.Data.Filter, Balances: r.Data.Balances}
+
+		if r.Data.FromHeight > 0 || r.Data.FromTxID != "" {
+			if c.replayHandler == nil {
+				c.writeError(ErrorCodeReplayNotSupported, "replay not supported", r.SubscriptionID)
+				return
+			}
+
+			// register the buffered subscriber before replaying so a subsequent unsubscribe or stop always removes it
+			buffer := &replaySubscriber{clientID: c.clientID, target: c, overflow: c.replayOverflow}
+
+			// the replay is cancelled if the subscription is unsubscribed or the connection is stopped before it completes
+			ctx, cancel := context.WithCancel(c.ctx)
+			if cancelReplay, ok := c.replays[r.SubscriptionID]; ok {
+				cancelReplay()
+			}
+			c.replays[r.SubscriptionID] = cancel
+
+			c.addSubscription(r.SubscriptionID, addrs)
+			c.writeSubscription("subscribed", r.SubscriptionID, TopicTxs, addrs)
+			c.handler.Subscribe(c.clientID, r.SubscriptionID, addrs, buffer, opts)
+			go c.replay(ctx, r.SubscriptionID, addrs, r.Data.FromHeight, r.Data.FromTxID, opts, buffer)
+			return
+		}
+
+		c.addSubscription(r.SubscriptionID, addrs)
+		c.writeSubscription("subscribed", r.SubscriptionID, TopicTxs, addrs)
+		c.handler.Subscribe(c.clientID, r.SubscriptionID, addrs, c, opts)
+	case TopicBlocks:
+		c.addSubscription(r.SubscriptionID, nil)
+		c.topics[r.SubscriptionID] = r.Data.Topic
+		c.writeSubscription("subscribed", r.SubscriptionID, r.Data.Topic, nil)
+		c.handler.SubscribeTopic(c.clientID, r.SubscriptionID, r.Data.Topic, c)
+	default:
+		c.writeError(ErrorCodeUnknownTopic, fmt.Sprintf("subscribe method not implemented for topic: %s", r.Data.Topic), r.SubscriptionID)
+	}
+}
+
+func (c *Connection) handleUnsubscribe(r *RequestPayload) {
+	switch r.Data.Topic {
+	case "", TopicTxs:
+		if r.SubscriptionID != "" {
+			addrs := c.handler.Unsubscribe(c.clientID, r.SubscriptionID, r.Data.Addresses, c)
+			c.removeSubscription(r.SubscriptionID, r.Data.Addresses)
+			c.writeSubscription("unsubscribed", r.SubscriptionID, TopicTxs, addrs)
+		} else {
+			subscriptionIDs := maps.Keys(c.subscriptions)
+			for subscriptionID := range subscriptionIDs {
+				// topic subscriptions are only unsubscribed by topic
+				if _, ok := c.topics[subscriptionID]; ok {
+					continue
+				}
+
+				addrs := c.handler.Unsubscribe(c.clientID, subscriptionID, r.Data.Addresses, c)
+				c.removeSubscription(subscriptionID, r.Data.Addresses)
+				c.writeSubscription("unsubscribed", subscriptionID, TopicTxs, addrs)
+			}
+		}
+	case TopicBlocks:
+		if r.SubscriptionID != "" {
+			c.handler.UnsubscribeTopic(c.clientID, r.SubscriptionID, r.Data.Topic, c)
+			c.removeSubscription(r.SubscriptionID, nil)
+			c.writeSubscription("unsubscribed", r.SubscriptionID, r.Data.Topic, nil)
+		} else {
+			subscriptionIDs := maps.Keys(c.subscriptions)
+			for subscriptionID := range subscriptionIDs {
+				if c.topics[subscriptionID] != r.Data.Topic {
+					continue
+				}
+
+				c.handler.UnsubscribeTopic(c.clientID, subscriptionID, r.Data.Topic, c)
+				c.removeSubscription(subscriptionID, nil)
+			}
+			c.writeSubscription("unsubscribed", r.SubscriptionID, r.Data.Topic, nil)
+		}
+	default:
+		c.writeError(ErrorCodeUnknownTopic, fmt.Sprintf("unsubscribe method not implemented for topic: %s", r.Data.Topic), r.SubscriptionID)
+	}
+}
+
+// addSubscription tracks the addresses subscribed to for enforcing connection limits
+// subscriptionTopic returns the topic of an existing subscription
+func (c *Connection) subscriptionTopic(subscriptionID string) (string, bool) {
+	if _, ok := c.subscriptions[subscriptionID]; !ok {
+		return "", false
+	}
+
+	if topic, ok := c.topics[subscriptionID]; ok {
+		return topic, true
+	}
+
+	return TopicTxs, true
+}
+
+// requestTopic returns the topic of a request, which defaults to txs if not provided
+func requestTopic(topic string) string {
+	if topic == "" {
+		return TopicTxs
+	}
+
+	return topic
+}
+
+func (c *Connection) addSubscription(subscriptionID string, addrs []string) {
+	prometheus := c.manager.prometheus.Metrics
+
+	if _, ok := c.subscriptions[subscriptionID]; !ok {
+		c.subscriptions[subscriptionID] = make(map[string]struct{})
+		prometheus.WebsocketSubscriptionCount.Inc()
+	}
+
+	for _, addr := range addrs {
+		if _, ok := c.subscriptions[subscriptionID][addr]; ok {
+			continue
+		}
+
+		c.subscriptions[subscriptionID][addr] = struct{}{}
+		c.addressCount++
+		prometheus.WebsocketAddressCount.Inc()
+	}
+}
+
+// removeSubscription stops tracking the addresses provided, or the entire subscription if no addresses are provided
+func (c *Connection) removeSubscription(subscriptionID string, addrs []string) {
+	prometheus := c.manager.prometheus.Metrics
+
+	subscribed, ok := c.subscriptions[subscriptionID]
+	if !ok {
+		return
+	}
+
+	if len(addrs) == 0 {
+		if cancelReplay, ok := c.replays[subscriptionID]; ok {
+			cancelReplay()
+			delete(c.replays, subscriptionID)
+		}
+
+		c.addressCount -= len(subscribed)
+		prometheus.WebsocketAddressCount.Sub(float64(len(subscribed)))
+		prometheus.WebsocketSubscriptionCount.Dec()
+		delete(c.subscriptions, subscriptionID)
+		delete(c.topics, subscriptionID)
+		return
+	}
+
+	for _, addr := range addrs {
+		if _, ok := subscribed[addr]; !ok {
+			continue
+		}
+
+		delete(subscribed, addr)
+		c.addressCount--
+		prometheus.WebsocketAddressCount.Dec()
+	}
+}
+
+// validateAddresses returns the unique addresses requested, or writes an error response if any address is not accepted
+func (c *Connection) validateAddresses(subscriptionID string, addrs []string) ([]string, bool) {
+	if len(addrs) == 0 {
+		c.writeError(ErrorCodeAddressesRequired, "addresses required", subscriptionID)
+		return nil, false
+	}
+
+	unique := make([]string, 0, len(addrs))
+	seen := make(map[string]struct{}, len(addrs))
+	invalid := []string{}
+
+	for _, addr := range addrs {
+		if _, ok := seen[addr]; ok {
+			continue
+		}
+		seen[addr] = struct{}{}
+
+		if c.addressValidator != nil && !c.addressValidator(addr) {
+			invalid = append(invalid, addr)
+			continue
+		}
+
+		unique = append(unique, addr)
+	}
+
+	if len(invalid) > 0 {
+		c.writeError(ErrorCodeInvalidAddress, fmt.Sprintf("invalid addresses: %s", strings.Join(invalid, ",")), subscriptionID)
+		return nil, false
+	}
+
+	if maxAddresses := c.manager.config.MaxAddresses; len(unique) > maxAddresses {
+		c.manager.prometheus.Metrics.WebsocketLimitExceeded.With(metrics.Labels{"limit": "addresses"}).Inc()
+		c.writeError(ErrorCodeTooManyAddresses, fmt.Sprintf("too many addresses: %d (max: %d)", len(unique), maxAddresses), subscriptionID)
+		return nil, false
+	}
+
+	added := 0
+	for _, addr := range unique {
+		if _, ok := c.subscriptions[subscriptionID][addr]; !ok {
+			added++
+		}
+	}
+
+	if maxAddresses := c.manager.config.MaxConnectionAddresses; c.addressCount+added > maxAddresses {
+		c.manager.prometheus.Metrics.WebsocketLimitExceeded.With(metrics.Labels{"limit": "connection_addresses"}).Inc()
+		c.writeError(ErrorCodeTooManyAddresses, fmt.Sprintf("too many addresses for connection: %d (max: %d)", c.addressCount+added, maxAddresses), subscriptionID)
+		return nil, false
+	}
+
+	return unique, true
+}
+
+// replay any missed messages for the subscription before switching over to live delivery.
+// live messages published while the replay is in progress are buffered by the subscriber provided and delivered after the replayed messages.
+func (c *Connection) replay(ctx context.Context, subscriptionID string, addrs []string, fromHeight int, fromTxID string, opts SubscribeOptions, buffer *replaySubscriber) {
+	// replayed data and matched addresses in the order first seen, used to send duplicate data once if deduplicating
+	replayed := []*replayedMessage{}
+	seen := make(map[string]*replayedMessage)
+
+	for _, addr := range addrs {
+		if ctx.Err() != nil {
+			return
+		}
+
+		data, err := c.replayHandler(ctx, addr, fromHeight, fromTxID)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err != nil {
+			logger.Errorf("failed to replay messages for address: %s: %+v", addr, err)
+			c.writeError(ErrorCodeReplayFailed, fmt.Sprintf("failed to replay messages for address: %s", addr), subscriptionID)
+			continue
+		}
+
+		logger.Debugf("Replay: clientID: %s, subscriptionID: %s, address: %s, messages: %d", c.clientID, subscriptionID, addr, len(data))
+
+		for _, d := range data {
+			if opts.Filter != nil && !opts.Filter.match(addr, parseFilterData(d)) {
+				continue
+			}
+
+			if opts.Dedupe {
+				raw, err := json.Marshal(d)
+				if err != nil {
+					logger.Errorf("failed to marshal replay message: %v", err)
+					continue
+				}
+
+				if m, ok := seen[string(raw)]; ok {
+					m.addrs = append(m.addrs, addr)
+					continue
 				}
+
+				m := &replayedMessage{addrs: []string{addr}, data: json.RawMessage(raw)}
+				seen[string(raw)] = m
+				replayed = append(replayed, m)
+				continue
+			}
+
+			msg, err := json.Marshal(MessageResponse{Address: addr, Data: d, SubscriptionID: subscriptionID})
+			if err != nil {
+				logger.Errorf("failed to marshal replay message: %v", err)
+				continue
 			}
+
+			if ctx.Err() != nil || !c.sendWait(NewMessage(msg)) {
+				return
+			}
+		}
+	}
+
+	for _, m := range replayed {
+		msg, err := json.Marshal(MessageResponse{Addresses: m.addrs, Data: m.data, SubscriptionID: subscriptionID})
+		if err != nil {
+			logger.Errorf("failed to marshal replay message: %v", err)
+			continue
+		}
+
+		if ctx.Err() != nil || !c.sendWait(NewMessage(msg)) {
+			return
+		}
+	}
+
+	buffer.flush()
+}
+
+type replayedMessage struct {
+	addrs []string
+	data  json.RawMessage
+}
+
+// replaySubscriber buffers live messages until the replay is complete and then passes them straight through to the target.
+// if the buffer fills up before the replay is complete, overflow is called to disconnect the client instead of leaving a gap in delivery.
+type replaySubscriber struct {
+	buffer     []*Message
+	clientID   string
+	live       bool
+	overflow   func()
+	overflowed bool
+	target     Subscriber
+	m          sync.Mutex
+}
+
+func (r *replaySubscriber) Send(msg *Message) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if r.live {
+		r.target.Send(msg)
+		return
+	}
+
+	if r.overflowed {
+		return
+	}
+
+	if len(r.buffer) >= replayBuffer {
+		logger.Warnf("replay buffer full: disconnecting clientID: %s", r.clientID)
+		r.overflowed = true
+		r.buffer = nil
+		r.overflow()
+		return
+	}
+
+	r.buffer = append(r.buffer, msg)
+}
+
+// flush buffered messages to the target and switch over to live delivery
+func (r *replaySubscriber) flush() {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	// client is being disconnected, delivering the buffered messages would leave a gap
+	if r.overflowed {
+		return
+	}
+
+	for _, msg := range r.buffer {
+		r.target.Send(msg)
+	}
+
+	r.buffer = nil
+	r.live = true
+}
+
+// replayOverflow disconnects the client if live messages overflow the replay buffer so the client can resume without missing any messages
+func (c *Connection) replayOverflow() {
+	if c.overflowed.CompareAndSwap(false, true) {
+		go c.close(websocket.ClosePolicyViolation, "slow consumer: replay buffer full")
+	}
+}
+
+// Send a message to the client without blocking by adding it to the bounded outbound queue.
+// if the queue is full, the configured overflow policy is applied.
+func (c *Connection) Send(msg *Message) {
+	c.qm.RLock()
+	defer c.qm.RUnlock()
+
+	// connection is closing, discard any remaining messages
+	if c.queueClosed || c.overflowed.Load() {
+		return
+	}
+
+	prometheus := c.manager.prometheus.Metrics
+
+	select {
+	case c.queue <- msg:
+		prometheus.WebsocketQueueDepth.Inc()
+		return
+	default:
+	}
+
+	switch c.manager.config.QueueOverflowPolicy {
+	case OverflowDisconnect:
+		prometheus.WebsocketDroppedMessages.With(metrics.Labels{"policy": string(OverflowDisconnect)}).Inc()
+		if c.overflowed.CompareAndSwap(false, true) {
+			logger.Warnf("queue full: disconnecting clientID: %s", c.clientID)
+			go c.close(websocket.ClosePolicyViolation, "slow consumer: message queue full")
+		}
+	default:
+		select {
+		case <-c.queue:
+			prometheus.WebsocketQueueDepth.Dec()
+			prometheus.WebsocketDroppedMessages.With(metrics.Labels{"policy": string(OverflowDropOldest)}).Inc()
+		default:
+		}
+
+		select {
+		case c.queue <- msg:
+			prometheus.WebsocketQueueDepth.Inc()
 		default:
-			c.writeError(fmt.Sprintf("%s method not implemented", r.Method), r.SubscriptionID)
+			prometheus.WebsocketDroppedMessages.With(metrics.Labels{"policy": string(OverflowDropOldest)}).Inc()
 		}
 	}
 }
 
+// sendWait adds a message to the outbound queue, waiting for room instead of applying the overflow policy.
+// returns false if the connection was closed before the message could be queued.
+func (c *Connection) sendWait(msg *Message) bool {
+	c.qm.RLock()
+	defer c.qm.RUnlock()
+
+	if c.queueClosed {
+		return false
+	}
+
+	select {
+	case c.queue <- msg:
+		c.manager.prometheus.Metrics.WebsocketQueueDepth.Inc()
+		return true
+	case <-c.doneChan:
+		return false
+	}
+}
+
 func (c *Connection) write() {
-	for msg := range c.msgChan {
+	for msg := range c.queue {
+		c.manager.prometheus.Metrics.WebsocketQueueDepth.Dec()
+
 		if err := c.conn.SetWriteDeadline(time.Now().Add(writeWait)); err != nil {
 			logger.Errorf("failed to set write deadline: %+v", err)
 		}
-		if err := c.send(websocket.TextMessage, msg); err != nil {
+		messageType, data, err := msg.Encode(c.encoding)
+		if err != nil {
+			logger.Errorf("failed to encode message: %+v", err)
+			continue
+		}
+
+		// any messages still queued after the close frame has been sent are discarded
+		if err := c.send(messageType, data); err != nil && err != websocket.ErrCloseSent {
 			logger.Errorf("failed to write message: %+v", err)
 		}
 	}
 }
 
-func (c *Connection) writeError(message string, subscriptionID string) {
+// writeSubscription acknowledges a subscribe or unsubscribe request.
+// the acknowledgement is queued behind any messages already published to the client to preserve ordering.
+func (c *Connection) writeSubscription(t string, subscriptionID string, topic string, addrs []string) {
+	s := SubscriptionResponse{
+		Addresses:      addrs,
+		SubscriptionID: subscriptionID,
+		Topic:          topic,
+		Type:           t,
+	}
+
+	msg, err := json.Marshal(s)
+	if err != nil {
+		logger.Errorf("failed to marshal subscription response: %v", err)
+		return
+	}
+
+	c.sendWait(NewMessage(msg))
+}
+
+func (c *Connection) writeError(code ErrorCode, message string, subscriptionID string) {
 	e := ErrorResponse{
+		Code:           code,
 		SubscriptionID: subscriptionID,
 		Type:           "error",
 		Message:        message,
@@ -223,10 +824,16 @@ func (c *Connection) writeError(message string, subscriptionID string) {
 		return
 	}
 
+	messageType, data, err := NewMessage(msg).Encode(c.encoding)
+	if err != nil {
+		logger.Errorf("failed to encode error response: %+v", err)
+		return
+	}
+
 	if err := c.conn.SetWriteDeadline(time.Now().Add(writeWait)); err != nil {
 		logger.Errorf("failed to set write deadline: %+v", err)
 	}
-	if err := c.send(websocket.TextMessage, msg); err != nil {
+	if err := c.send(messageType, data); err != nil {
 		logger.Errorf("failed to write message: %+v", err)
 	}
 }
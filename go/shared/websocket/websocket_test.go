@@ -0,0 +1,315 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/shapeshift/unchained/shared/metrics"
+	"golang.org/x/time/rate"
+)
+
+// newTestConnection creates a connection without an underlying websocket to exercise request handling directly
+func newTestConnection(handler Registrar) *Connection {
+	manager := NewManager(metrics.NewPrometheus("test"))
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Connection{
+		cancel:        cancel,
+		clientID:      "client",
+		ctx:           ctx,
+		doneChan:      make(chan interface{}),
+		handler:       handler,
+		manager:       manager,
+		queue:         make(chan *Message, manager.config.QueueSize),
+		rateLimiter:   rate.NewLimiter(rate.Limit(manager.config.SubscribeRate), manager.config.SubscribeRate),
+		replays:       make(map[string]context.CancelFunc),
+		subscriptions: make(map[string]map[string]struct{}),
+		topics:        make(map[string]string),
+	}
+}
+
+func request(t *testing.T, req string) *RequestPayload {
+	t.Helper()
+
+	r := &RequestPayload{}
+	if err := json.Unmarshal([]byte(req), r); err != nil {
+		t.Fatalf("failed to unmarshal request: %+v", err)
+	}
+
+	return r
+}
+
+// recordingRegistrar records the subscribe and unsubscribe calls made to the registry
+type recordingRegistrar struct {
+	*Registry
+	calls []string
+	m     sync.Mutex
+}
+
+func (r *recordingRegistrar) Subscribe(clientID string, subscriptionID string, addrs []string, subscriber Subscriber, opts SubscribeOptions) {
+	r.m.Lock()
+	r.calls = append(r.calls, "subscribe:"+subscriptionID)
+	r.m.Unlock()
+
+	r.Registry.Subscribe(clientID, subscriptionID, addrs, subscriber, opts)
+}
+
+func (r *recordingRegistrar) Unsubscribe(clientID string, subscriptionID string, addrs []string, subscriber Subscriber) []string {
+	r.m.Lock()
+	r.calls = append(r.calls, "unsubscribe:"+subscriptionID)
+	r.m.Unlock()
+
+	return r.Registry.Unsubscribe(clientID, subscriptionID, addrs, subscriber)
+}
+
+func TestReplayUnsubscribedBeforeReplay(t *testing.T) {
+	registrar := &recordingRegistrar{Registry: NewRegistry()}
+	c := newTestConnection(registrar)
+
+	started := make(chan struct{})
+	cancelled := make(chan struct{})
+	c.ReplayHandler(func(ctx context.Context, addr string, fromHeight int, fromTxID string) ([]interface{}, error) {
+		close(started)
+		<-ctx.Done()
+		close(cancelled)
+		return nil, ctx.Err()
+	})
+
+	c.handleSubscribe(request(t, `{"method":"subscribe","subscriptionId":"1","data":{"topic":"txs","addresses":["addr1"],"fromHeight":1}}`))
+	<-started
+	c.handleUnsubscribe(request(t, `{"method":"unsubscribe","subscriptionId":"1","data":{"topic":"txs"}}`))
+
+	// the unsubscribe cancels the replay in progress
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected replay to be cancelled by the unsubscribe")
+	}
+
+	registrar.m.Lock()
+	defer registrar.m.Unlock()
+
+	// the subscription must be registered before the unsubscribe so no registry entry is left behind
+	if len(registrar.calls) != 2 || registrar.calls[0] != "subscribe:1" || registrar.calls[1] != "unsubscribe:1" {
+		t.Fatalf("unexpected registry calls: %v", registrar.calls)
+	}
+}
+
+type testSubscriber struct {
+	msgs [][]byte
+}
+
+func (s *testSubscriber) Send(msg *Message) {
+	data, err := msg.JSON()
+	if err != nil {
+		return
+	}
+
+	s.msgs = append(s.msgs, data)
+}
+
+func TestReplaySubscriberOverflow(t *testing.T) {
+	target := &testSubscriber{}
+	overflowed := 0
+
+	buffer := &replaySubscriber{clientID: "client", target: target, overflow: func() { overflowed++ }}
+
+	for i := 0; i <= replayBuffer+1; i++ {
+		buffer.Send(NewMessage([]byte("msg")))
+	}
+
+	if overflowed != 1 {
+		t.Fatalf("expected overflow to be called once, got: %d", overflowed)
+	}
+
+	// buffered messages are not delivered once overflowed as the client would miss messages
+	buffer.flush()
+
+	if len(target.msgs) != 0 {
+		t.Fatalf("expected no messages delivered after overflow, got: %d", len(target.msgs))
+	}
+}
+
+func TestUnsubscribeWithoutSubscriptionID(t *testing.T) {
+	registry := NewRegistry()
+	c := newTestConnection(registry)
+
+	c.handleSubscribe(request(t, `{"method":"subscribe","subscriptionId":"blocks","data":{"topic":"blocks"}}`))
+	c.handleSubscribe(request(t, `{"method":"subscribe","subscriptionId":"txs","data":{"topic":"txs","addresses":["addr1"]}}`))
+
+	// unsubscribing txs leaves the blocks subscription in place
+	c.handleUnsubscribe(request(t, `{"method":"unsubscribe","data":{"topic":"txs"}}`))
+
+	if _, ok := c.subscriptions["txs"]; ok {
+		t.Fatal("expected txs subscription to be removed")
+	}
+
+	if _, ok := c.subscriptions["blocks"]; !ok {
+		t.Fatal("expected blocks subscription to remain")
+	}
+
+	registry.topicsM.RLock()
+	_, ok := registry.topics[TopicBlocks][toID(c.clientID, "blocks")]
+	registry.topicsM.RUnlock()
+
+	if !ok {
+		t.Fatal("expected blocks topic to remain registered")
+	}
+
+	// unsubscribing blocks removes the subscription so it no longer counts toward the subscription limit
+	c.handleUnsubscribe(request(t, `{"method":"unsubscribe","data":{"topic":"blocks"}}`))
+
+	if len(c.subscriptions) != 0 || len(c.topics) != 0 {
+		t.Fatalf("expected no subscriptions, got: %v", c.subscriptions)
+	}
+}
+
+// newConnPair returns both ends of a websocket connection, leaving the server end for the test to use directly
+func newConnPair(t *testing.T) (*websocket.Conn, *websocket.Conn) {
+	t.Helper()
+
+	conns := make(chan *websocket.Conn, 1)
+	upgrader := websocket.Upgrader{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		conns <- conn
+	}))
+	t.Cleanup(server.Close)
+
+	client := dial(t, server, nil)
+
+	select {
+	case conn := <-conns:
+		t.Cleanup(func() { conn.Close() })
+		return conn, client
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for connection")
+	}
+
+	return nil, nil
+}
+
+// expectClose fails the test unless the next message read is a close frame with the code provided
+func expectClose(t *testing.T, conn *websocket.Conn, code int) {
+	t.Helper()
+
+	if err := conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatalf("failed to set read deadline: %+v", err)
+	}
+
+	_, _, err := conn.ReadMessage()
+	if !websocket.IsCloseError(err, code) {
+		t.Fatalf("expected close error with code %d, got: %v", code, err)
+	}
+}
+
+func TestOverflowDropOldest(t *testing.T) {
+	c := newTestConnection(NewRegistry())
+	c.queue = make(chan *Message, 2)
+
+	for _, msg := range []string{"1", "2", "3"} {
+		c.Send(NewMessage([]byte(msg)))
+	}
+
+	if c.overflowed.Load() {
+		t.Fatal("expected connection not to be disconnected")
+	}
+
+	for _, expected := range []string{"2", "3"} {
+		msg, err := (<-c.queue).JSON()
+		if err != nil || string(msg) != expected {
+			t.Fatalf("expected message %s, got %s: %v", expected, msg, err)
+		}
+	}
+}
+
+func TestOverflowDisconnect(t *testing.T) {
+	manager := NewManager(metrics.NewPrometheus("test"))
+	manager.config.QueueSize = 2
+	manager.config.QueueOverflowPolicy = OverflowDisconnect
+	go manager.Start()
+
+	conn, client := newConnPair(t)
+
+	// the connection is not started so the queue is never drained
+	c := NewConnection(conn, NewRegistry(), manager)
+
+	for _, msg := range []string{"1", "2", "3", "4"} {
+		c.Send(NewMessage([]byte(msg)))
+	}
+
+	if !c.overflowed.Load() {
+		t.Fatal("expected connection to be disconnected")
+	}
+
+	// queued messages are kept, with any messages sent after the overflow discarded
+	if n := len(c.queue); n != 2 {
+		t.Fatalf("expected 2 queued messages, got %d", n)
+	}
+
+	expectClose(t, client, websocket.ClosePolicyViolation)
+}
+
+func TestSubscribeTopicMismatch(t *testing.T) {
+	manager := NewManager(metrics.NewPrometheus("test"))
+	go manager.Start()
+
+	conn, client := newConnPair(t)
+	registry := NewRegistry()
+
+	// the connection is not started, error responses are written directly to the client
+	c := NewConnection(conn, registry, manager)
+
+	c.handleSubscribe(request(t, `{"method":"subscribe","subscriptionId":"1","data":{"topic":"txs","addresses":["addr1"]}}`))
+
+	// reusing the subscription id for another topic is rejected, leaving the existing subscription in place
+	c.handleSubscribe(request(t, `{"method":"subscribe","subscriptionId":"1","data":{"topic":"blocks"}}`))
+
+	if msg := readMessage(t, client, websocket.TextMessage); msg["code"] != string(ErrorCodeTopicMismatch) {
+		t.Fatalf("expected %s error, got: %v", ErrorCodeTopicMismatch, msg)
+	}
+
+	if _, ok := c.topics["1"]; ok {
+		t.Fatal("expected subscription not to be changed to the blocks topic")
+	}
+
+	if _, ok := c.subscriptions["1"]["addr1"]; !ok {
+		t.Fatal("expected existing address subscription to be kept")
+	}
+
+	registry.topicsM.RLock()
+	topics := len(registry.topics)
+	registry.topicsM.RUnlock()
+
+	if topics != 0 {
+		t.Fatal("expected no topic subscription to be registered")
+	}
+
+	// resubscribing with the same topic, including the default topic, is allowed
+	c.handleSubscribe(request(t, `{"method":"subscribe","subscriptionId":"1","data":{"addresses":["addr2"]}}`))
+
+	if _, ok := c.subscriptions["1"]["addr2"]; !ok {
+		t.Fatal("expected address to be added to the existing subscription")
+	}
+
+	c.handleSubscribe(request(t, `{"method":"subscribe","subscriptionId":"2","data":{"topic":"blocks"}}`))
+	c.handleSubscribe(request(t, `{"method":"subscribe","subscriptionId":"2","data":{"topic":"txs","addresses":["addr3"]}}`))
+
+	if msg := readMessage(t, client, websocket.TextMessage); msg["code"] != string(ErrorCodeTopicMismatch) {
+		t.Fatalf("expected %s error, got: %v", ErrorCodeTopicMismatch, msg)
+	}
+
+	if _, ok := c.subscriptions["2"]["addr3"]; ok {
+		t.Fatal("expected blocks subscription not to be reused for addresses")
+	}
+}